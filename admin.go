@@ -0,0 +1,154 @@
+package honu
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/bbengfort/honu/rpc"
+)
+
+//===========================================================================
+// Server Admin RPC methods
+//===========================================================================
+
+// Status replies with the state of the replica, its store and its peers.
+func (s *Server) Status(ctx context.Context, in *pb.StatusRequest) (*pb.StatusReply, error) {
+	nkeys := s.nkeys()
+
+	s.Lock()
+	defer s.Unlock()
+
+	reply := &pb.StatusReply{
+		Pid:      s.members.local.PID,
+		Addr:     s.addr,
+		Cluster:  s.cluster,
+		Store:    s.stype,
+		Uptime:   time.Since(s.running).Seconds(),
+		Keys:     uint64(nkeys),
+		Versions: s.store.Versions(),
+		Reads:    s.reads,
+		Writes:   s.writes,
+		LogLevel: LogLevels(),
+		Peers:    make([]*pb.PeerStatus, 0, len(s.peers)),
+	}
+
+	if s.bandit == nil {
+		return reply, nil
+	}
+
+	params := s.bandit.Serialize().(map[string]interface{})
+	reply.Bandit = fmt.Sprintf("%v", params["strategy"])
+
+	counts := s.bandit.Counts()
+	values := s.bandit.Values()
+	for arm, peer := range s.peers {
+		status := &pb.PeerStatus{
+			Addr:  peer,
+			Count: counts[arm],
+			Value: values[arm],
+		}
+
+		if member, ok := s.members.Get(peer); ok {
+			status.Status = member.Status.String()
+		}
+
+		if metrics := s.syncs[peer]; metrics != nil {
+			status.Syncs = metrics.Syncs
+			status.Pulls = metrics.Pulls
+			status.Pushes = metrics.Pushes
+			status.Misses = metrics.Misses
+			status.Versions = metrics.Versions
+			status.Sent = metrics.Sent
+			status.Received = metrics.Received
+			status.PullLatency = metrics.PullLatency.Mean().Seconds()
+			status.PushLatency = metrics.PushLatency.Mean().Seconds()
+
+			if !metrics.LastSync.IsZero() {
+				status.LastSync = metrics.LastSync.Format(time.RFC3339)
+			}
+		}
+
+		reply.Peers = append(reply.Peers, status)
+	}
+
+	return reply, nil
+}
+
+// SetLogLevel changes the log level of the replica and its components at
+// runtime, e.g. "info" or "caution,gossip=debug".
+func (s *Server) SetLogLevel(ctx context.Context, in *pb.AdminRequest) (*pb.AdminReply, error) {
+	if err := SetLogLevels(in.Level); err != nil {
+		return &pb.AdminReply{Success: false, Error: err.Error()}, nil
+	}
+
+	levels := LogLevels()
+	status("log level set to %s", levels)
+	return &pb.AdminReply{Success: true, Message: fmt.Sprintf("log level set to %s", levels)}, nil
+}
+
+// TriggerAntiEntropy performs an anti-entropy session immediately, in
+// addition to the scheduled sessions, and replies with its outcome.
+func (s *Server) TriggerAntiEntropy(ctx context.Context, in *pb.AdminRequest) (*pb.AdminReply, error) {
+	if s.bandit == nil {
+		return &pb.AdminReply{Success: false, Error: "replica is not replicating"}, nil
+	}
+
+	session := new(Synchronization)
+	s.synchronize(session)
+
+	if session.Peer == "" {
+		return &pb.AdminReply{Success: false, Error: "no live peers to perform anti-entropy with"}, nil
+	}
+
+	msg := fmt.Sprintf("synchronized %d items with %s in %s", session.Items(), session.Peer, session.Latency())
+	return &pb.AdminReply{Success: true, Message: msg}, nil
+}
+
+// SnapshotHistory writes the version history of the store to the history
+// path of the server. Since admin requests are not authenticated, a requested
+// path is refused unless it is the history path.
+func (s *Server) SnapshotHistory(ctx context.Context, in *pb.AdminRequest) (*pb.AdminReply, error) {
+	path, err := adminPath(in.Path, s.history, "version history")
+	if err != nil {
+		return &pb.AdminReply{Success: false, Error: err.Error()}, nil
+	}
+
+	if err := s.store.Snapshot(path); err != nil {
+		return &pb.AdminReply{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.AdminReply{Success: true, Message: fmt.Sprintf("version history snapshot saved to %s", path)}, nil
+}
+
+// DumpMetrics appends the current server metrics to the stats path of the
+// server. Since admin requests are not authenticated, a requested path is
+// refused unless it is the stats path.
+func (s *Server) DumpMetrics(ctx context.Context, in *pb.AdminRequest) (*pb.AdminReply, error) {
+	path, err := adminPath(in.Path, s.stats, "metrics")
+	if err != nil {
+		return &pb.AdminReply{Success: false, Error: err.Error()}, nil
+	}
+
+	if err := s.Metrics(path); err != nil {
+		return &pb.AdminReply{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.AdminReply{Success: true, Message: fmt.Sprintf("server metrics saved to %s", path)}, nil
+}
+
+// adminPath returns the path the server was configured to write the output
+// to, refusing requests for any other path.
+func adminPath(requested, configured, output string) (string, error) {
+	if configured == "" {
+		return "", fmt.Errorf("server was not configured with a path to write the %s to", output)
+	}
+
+	if requested != "" && filepath.Clean(requested) != filepath.Clean(configured) {
+		return "", fmt.Errorf("can only write the %s to %s", output, configured)
+	}
+
+	return configured, nil
+}
@@ -1,101 +1,535 @@
 package honu
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"math/rand"
+	"strings"
 )
 
+// BanditOptions are the parameters of the bandit strategies; each strategy
+// only uses the options that apply to it.
+type BanditOptions struct {
+	Epsilon     float64 `json:"epsilon,omitempty"`     // Probability of exploration for epsilon greedy
+	Temperature float64 `json:"temperature,omitempty"` // Temperature of the softmax (Boltzmann) distribution
+	Gamma       float64 `json:"gamma,omitempty"`       // Exploration rate of Exp3
+	Confidence  float64 `json:"confidence,omitempty"`  // Number of standard deviations for Bayesian UCB and LinUCB
+	Decay       float64 `json:"decay,omitempty"`       // Constant step size of the reward estimates, 0 for the mean
+	Discount    float64 `json:"discount,omitempty"`    // Discount factor of past rewards, 0 for no discounting
+	Window      int     `json:"window,omitempty"`      // Number of recent rewards to estimate from, 0 for all
+	History     int     `json:"history,omitempty"`     // Number of recent rewards kept in memory, 0 for the default
+}
+
+// NewBandit creates the bandit strategy with the specified name. By default
+// the strategies estimate the reward of each arm with the mean of all of its
+// rewards; in non-stationary environments one of the decay, discount or
+// window options can be specified so that the estimates favor recent rewards,
+// except for linucb, whose ridge regression weighs all rewards equally.
+// The discounted-ucb and sliding-window-ucb strategies are UCB1 with a
+// default discount of 0.99 or window of 100 rewards respectively.
+func NewBandit(strategy string, opts BanditOptions) (BanditStrategy, error) {
+	var bandit BanditStrategy
+
+	switch strings.ToLower(strategy) {
+	case "uniform":
+		bandit = new(Uniform)
+	case "epsilon":
+		bandit = &EpsilonGreedy{Epsilon: opts.Epsilon}
+	case "annealing":
+		bandit = new(AnnealingEpsilonGreedy)
+	case "ucb1":
+		bandit = new(UCB1)
+	case "discounted-ucb":
+		if opts.Discount == 0 {
+			opts.Discount = 0.99
+		}
+		bandit = new(UCB1)
+	case "sliding-window-ucb":
+		if opts.Window == 0 {
+			opts.Window = 100
+		}
+		bandit = new(UCB1)
+	case "bayes-ucb":
+		bandit = &BayesianUCB{Confidence: opts.Confidence}
+	case "thompson-beta":
+		bandit = new(BetaThompsonSampling)
+	case "thompson-gaussian":
+		bandit = new(GaussianThompsonSampling)
+	case "softmax":
+		if opts.Temperature <= 0 {
+			return nil, fmt.Errorf("softmax temperature must be positive, not %f", opts.Temperature)
+		}
+		bandit = &Softmax{Temperature: opts.Temperature}
+	case "exp3":
+		if opts.Gamma <= 0 || opts.Gamma > 1 {
+			return nil, fmt.Errorf("exp3 gamma must be in (0, 1], not %f", opts.Gamma)
+		}
+		bandit = &Exp3{Gamma: opts.Gamma}
+	case "linucb":
+		if opts.Decay > 0 || opts.Discount > 0 || opts.Window > 0 {
+			return nil, errors.New("linucb does not support reward decay, discount or window")
+		}
+		bandit = &LinUCB{Alpha: opts.Confidence, Features: PeerFeatures}
+	default:
+		return nil, fmt.Errorf("no peer selection bandit strategy named  %s", strategy)
+	}
+
+	if err := bandit.(estimator).arms().configure(opts); err != nil {
+		return nil, err
+	}
+	return bandit, nil
+}
+
+// estimator is implemented by all strategies that embed banditArms so that
+// the reward estimation can be configured when the strategy is created, and
+// what was learned about each arm can be checkpointed and restored.
+type estimator interface {
+	arms() *banditArms
+	checkpoint(arm int, state *ArmCheckpoint)
+	restore(arm int, state ArmCheckpoint, decay float64)
+}
+
 // BanditStrategy specifies the methods required by an algorithm to compute
 // multi-armed bandit probabilities for reinforcement learning. The basic
 // mechanism allows you to initialize a strategy with n arms (or n choices).
 // The Select() method will return a selected index based on the internal
 // strategy, and the Update() method allows external callers to update the
-// reward function for the selected arm.
+// reward function for the selected arm. Arms can be added and removed at
+// runtime as replicas join and leave the cluster without resetting what has
+// been learned about the remaining arms, and arms can be temporarily disabled
+// to exclude them from selection. If no arms are enabled, Select returns -1.
 type BanditStrategy interface {
 	Init(nArms int)                 // Initialize the bandit with n choices
 	Select() int                    // Selects an arm and returns the index of the choice
 	Update(arm int, reward float64) // Update the given arm with a reward
+	Add() int                       // Add a new arm and return its index
+	Remove(arm int)                 // Remove an arm, shifting the index of later arms down
+	Enable(arm int)                 // Include the arm in selection (the default)
+	Disable(arm int)                // Exclude the arm from selection
 	Counts() []uint64               // The frequency of each arm being selected
 	Values() []float64              // The reward distributions for each arm
 	Serialize() interface{}         // Return a JSON representation of the strategy
 }
 
 //===========================================================================
-// Epsilon Greedy Multi-Armed Bandit
+// Bandit Arms - Shared Bookkeeping
 //===========================================================================
 
-// EpsilonGreedy implements a reinforcement learning strategy such that the
-// maximizing value is selected with probability epsilon and a uniform random
-// selection is made with probability 1-epsilon.
-type EpsilonGreedy struct {
-	Epsilon float64        // Probability of selecting maximizing value
-	counts  []uint64       // Number of times each index was selected
-	values  []float64      // Reward values conditioned by frequency
-	history *BanditHistory // History of reward values per iteration
+// banditArms implements the frequency and reward bookkeeping that is shared
+// by all bandit strategies. Strategies embed banditArms and implement Select
+// and Serialize to define how they choose between the arms.
+//
+// The value of each arm is estimated with the mean of its rewards unless the
+// arms are non-stationary: with a constant step size (decay) the estimate is
+// an exponential recency-weighted average of the rewards of the arm; with a
+// discount factor all past rewards of all arms are discounted every update;
+// and with a window only the most recent rewards are used. When discounting
+// or windowing, the effective number of selections of each arm is also
+// reduced so that the confidence in stale estimates shrinks.
+type banditArms struct {
+	counts      []uint64       // Number of times each index was selected
+	values      []float64      // Reward values conditioned by frequency
+	squares     []float64      // Squared reward values conditioned by frequency
+	disabled    []bool         // Arms that are excluded from selection
+	history     *BanditHistory // History of reward values per iteration
+	decay       float64        // Constant step size of the estimates (if > 0)
+	discount    float64        // Discount factor of past rewards (if > 0)
+	window      int            // Number of recent rewards in the estimates (if > 0)
+	weights     []float64      // Discounted or windowed number of selections
+	sums        []float64      // Discounted or windowed sum of rewards
+	sumsq       []float64      // Discounted or windowed sum of squared rewards
+	recent      []banditReward // Rewards in the sliding window, oldest first
+	historySize int            // Number of recent rewards kept in the history
 }
 
-// Init the bandit with nArms number of possible choices, which are referred
-// to by index in both the Counts and Values arrays.
-func (b *EpsilonGreedy) Init(nArms int) {
-	b.counts = make([]uint64, nArms, nArms)
-	b.values = make([]float64, nArms, nArms)
-	b.history = NewBanditHistory()
+// banditReward is a reward of an arm in the sliding window.
+type banditReward struct {
+	arm    int
+	reward float64
 }
 
-// Select the arm with the maximizing value with probability epsilon,
-// otherwise uniform random selection of all arms with probability 1-epsilon.
-func (b *EpsilonGreedy) Select() int {
-	if rand.Float64() > b.Epsilon {
-		// Select the maximal value from values.
-		max := -1.0
-		idx := -1
-
-		// Find the index of the maximal value.
-		for i, val := range b.values {
-			if val > max {
-				max = val
-				idx = i
-			}
+// arms returns the bookkeeping so that NewBandit can configure estimation.
+func (b *banditArms) arms() *banditArms {
+	return b
+}
+
+// configure how the rewards of the arms are estimated and recorded, returning
+// an error if more than one non-stationary estimate is specified.
+func (b *banditArms) configure(opts BanditOptions) error {
+	if opts.Decay < 0 || opts.Decay > 1 {
+		return fmt.Errorf("reward decay must be in (0, 1], not %f", opts.Decay)
+	}
+
+	if opts.Discount < 0 || opts.Discount >= 1 {
+		return fmt.Errorf("reward discount must be in (0, 1), not %f", opts.Discount)
+	}
+
+	if opts.Window < 0 {
+		return fmt.Errorf("reward window must be positive, not %d", opts.Window)
+	}
+
+	specified := 0
+	for _, set := range []bool{opts.Decay > 0, opts.Discount > 0, opts.Window > 0} {
+		if set {
+			specified++
 		}
+	}
 
-		return idx
+	if specified > 1 {
+		return errors.New("specify only one of reward decay, discount or window")
 	}
 
-	// Otherwise return any of the values
-	return rand.Intn(len(b.values))
+	b.decay = opts.Decay
+	b.discount = opts.Discount
+	b.window = opts.Window
+	b.historySize = opts.History
+	return nil
+}
+
+// Init the bandit with nArms number of possible choices, which are referred
+// to by index in both the Counts and Values arrays.
+func (b *banditArms) Init(nArms int) {
+	b.counts = make([]uint64, nArms, nArms)
+	b.values = make([]float64, nArms, nArms)
+	b.squares = make([]float64, nArms, nArms)
+	b.disabled = make([]bool, nArms, nArms)
+	b.weights = make([]float64, nArms, nArms)
+	b.sums = make([]float64, nArms, nArms)
+	b.sumsq = make([]float64, nArms, nArms)
+	b.recent = nil
+	b.history = NewBanditHistory(b.historySize)
 }
 
 // Update the selected arm with the reward so that the strategy can learn the
 // maximizing value (conditioned by the frequency of selection).
-func (b *EpsilonGreedy) Update(arm int, reward float64) {
+func (b *banditArms) Update(arm int, reward float64) {
 	// Update the frequency
 	b.counts[arm]++
-	n := float64(b.counts[arm])
 
-	value := b.values[arm]
-	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+	switch {
+	case b.window > 0:
+		// Add the reward to the window, evicting the oldest reward
+		b.recent = append(b.recent, banditReward{arm, reward})
+		b.accumulate(arm, reward, 1)
+
+		if len(b.recent) > b.window {
+			oldest := b.recent[0]
+			b.recent = b.recent[1:]
+			b.accumulate(oldest.arm, oldest.reward, -1)
+		}
+	case b.discount > 0:
+		// Discount the past rewards of all arms
+		for i := range b.weights {
+			b.weights[i] *= b.discount
+			b.sums[i] *= b.discount
+			b.sumsq[i] *= b.discount
+		}
+		b.accumulate(arm, reward, 1)
+	case b.decay > 0:
+		// Constant step size so that recent rewards are weighted more
+		b.values[arm] += b.decay * (reward - b.values[arm])
+		b.squares[arm] += b.decay * (reward*reward - b.squares[arm])
+	default:
+		n := float64(b.counts[arm])
+
+		value := b.values[arm]
+		b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+
+		square := b.squares[arm]
+		b.squares[arm] = ((n-1)/n)*square + (1/n)*reward*reward
+	}
 
 	b.history.Update(arm, reward)
 }
 
+// checkpoint the counts and estimates of the arm, along with its discounted
+// or windowed sums and its rewards in the sliding window.
+func (b *banditArms) checkpoint(arm int, state *ArmCheckpoint) {
+	state.Count = b.counts[arm]
+	state.Value = b.values[arm]
+	state.Square = b.squares[arm]
+
+	if b.discount > 0 || b.window > 0 {
+		state.Weight = b.weights[arm]
+		state.Sum = b.sums[arm]
+		state.SumSq = b.sumsq[arm]
+	}
+
+	for _, r := range b.recent {
+		if r.arm == arm {
+			state.Recent = append(state.Recent, r.reward)
+		}
+	}
+}
+
+// restore the checkpointed state of the arm, multiplying the number of
+// selections, the discounted sums and the number of rewards in the window by
+// the decay factor so that the confidence in the estimates is reduced. The
+// restored rewards are older than any in the window and are evicted first.
+// If the checkpoint has no discounted sums, e.g. because it was taken
+// without discounting, the effective number of selections is bounded by the
+// discount horizon; if it has no rewards in the window, the estimates are
+// kept until the arm is selected again.
+func (b *banditArms) restore(arm int, state ArmCheckpoint, decay float64) {
+	count := uint64(math.Floor(float64(state.Count)*decay + 0.5))
+	b.counts[arm] = count
+	b.values[arm] = state.Value
+	b.squares[arm] = state.Square
+
+	switch {
+	case b.window > 0:
+		keep := int(math.Floor(float64(len(state.Recent))*decay + 0.5))
+		recent := make([]banditReward, 0, keep+len(b.recent))
+		for _, reward := range state.Recent[len(state.Recent)-keep:] {
+			recent = append(recent, banditReward{arm, reward})
+			b.accumulate(arm, reward, 1)
+		}
+		b.recent = append(recent, b.recent...)
+
+		for len(b.recent) > b.window {
+			oldest := b.recent[0]
+			b.recent = b.recent[1:]
+			b.accumulate(oldest.arm, oldest.reward, -1)
+		}
+	case b.discount > 0 && state.Weight > 0:
+		b.weights[arm] = decay * state.Weight
+		b.sums[arm] = decay * state.Sum
+		b.sumsq[arm] = decay * state.SumSq
+	case b.discount > 0:
+		n := math.Min(float64(count), 1/(1-b.discount))
+		b.weights[arm] = n
+		b.sums[arm] = n * state.Value
+		b.sumsq[arm] = n * state.Square
+	}
+}
+
+// accumulate adds the weighted reward to the discounted or windowed sums of
+// the arm and updates its estimates. If the arm has no weight, for example
+// because all of its rewards have left the window, its last estimate is kept.
+func (b *banditArms) accumulate(arm int, reward, weight float64) {
+	b.weights[arm] += weight
+	b.sums[arm] += weight * reward
+	b.sumsq[arm] += weight * reward * reward
+
+	if b.weights[arm] <= 0 {
+		b.weights[arm], b.sums[arm], b.sumsq[arm] = 0, 0, 0
+		return
+	}
+
+	b.values[arm] = b.sums[arm] / b.weights[arm]
+	b.squares[arm] = b.sumsq[arm] / b.weights[arm]
+}
+
+// Add a new arm with no selections or reward and return its index.
+func (b *banditArms) Add() int {
+	b.counts = append(b.counts, 0)
+	b.values = append(b.values, 0)
+	b.squares = append(b.squares, 0)
+	b.disabled = append(b.disabled, false)
+	b.weights = append(b.weights, 0)
+	b.sums = append(b.sums, 0)
+	b.sumsq = append(b.sumsq, 0)
+	return len(b.values) - 1
+}
+
+// Remove the arm at the specified index; all arms after it are shifted down
+// by one index. Note that the history is not modified, so arms recorded in
+// the history refer to the index at the time of selection.
+func (b *banditArms) Remove(arm int) {
+	b.counts = append(b.counts[:arm], b.counts[arm+1:]...)
+	b.values = append(b.values[:arm], b.values[arm+1:]...)
+	b.squares = append(b.squares[:arm], b.squares[arm+1:]...)
+	b.disabled = append(b.disabled[:arm], b.disabled[arm+1:]...)
+	b.weights = append(b.weights[:arm], b.weights[arm+1:]...)
+	b.sums = append(b.sums[:arm], b.sums[arm+1:]...)
+	b.sumsq = append(b.sumsq[:arm], b.sumsq[arm+1:]...)
+
+	// Drop the rewards of the arm from the window and shift later arms down
+	recent := b.recent[:0]
+	for _, r := range b.recent {
+		if r.arm == arm {
+			continue
+		}
+		if r.arm > arm {
+			r.arm--
+		}
+		recent = append(recent, r)
+	}
+	b.recent = recent
+}
+
+// Enable the arm so that it can be selected.
+func (b *banditArms) Enable(arm int) {
+	b.disabled[arm] = false
+}
+
+// Disable the arm so that it is excluded from selection until enabled.
+func (b *banditArms) Disable(arm int) {
+	b.disabled[arm] = true
+}
+
 // Counts returns the frequency each arm was selected
-func (b *EpsilonGreedy) Counts() []uint64 {
+func (b *banditArms) Counts() []uint64 {
 	return b.counts
 }
 
 // Values returns the reward distribution of each arm
-func (b *EpsilonGreedy) Values() []float64 {
+func (b *banditArms) Values() []float64 {
 	return b.values
 }
 
-// Serialize the bandit strategy to dump to JSON.
-func (b *EpsilonGreedy) Serialize() interface{} {
+// greedy returns the index of the enabled arm with the maximal value.
+func (b *banditArms) greedy() int {
+	max := -1.0
+	idx := -1
+
+	// Find the index of the maximal value.
+	for i, val := range b.values {
+		if b.disabled[i] {
+			continue
+		}
+
+		if val > max || idx < 0 {
+			max = val
+			idx = i
+		}
+	}
+
+	return idx
+}
+
+// random returns the index of an enabled arm with uniform probability.
+func (b *banditArms) random() int {
+	enabled := b.enabled()
+	if len(enabled) == 0 {
+		return -1
+	}
+	return enabled[rand.Intn(len(enabled))]
+}
+
+// variance returns the sample variance of the rewards of the arm.
+func (b *banditArms) variance(arm int) float64 {
+	return math.Max(0, b.squares[arm]-b.values[arm]*b.values[arm])
+}
+
+// selections returns the effective number of selections of the arm, which
+// is discounted or windowed if the arms are non-stationary.
+func (b *banditArms) selections(arm int) float64 {
+	if b.discount > 0 || b.window > 0 {
+		return b.weights[arm]
+	}
+	return float64(b.counts[arm])
+}
+
+// trials returns the effective total number of selections of all arms.
+func (b *banditArms) trials() float64 {
+	t := 0.0
+	for arm := range b.counts {
+		t += b.selections(arm)
+	}
+	return t
+}
+
+// serialize the bookkeeping of the arms along with the strategy name.
+func (b *banditArms) serialize(strategy string) map[string]interface{} {
 	data := make(map[string]interface{})
-	data["strategy"] = "epsilon greedy"
-	data["epsilon"] = b.Epsilon
+	data["strategy"] = strategy
 	data["counts"] = b.counts
 	data["values"] = b.values
 	data["history"] = b.history
+
+	if b.decay > 0 {
+		data["decay"] = b.decay
+	}
+	if b.discount > 0 {
+		data["discount"] = b.discount
+	}
+	if b.window > 0 {
+		data["window"] = b.window
+	}
+	return data
+}
+
+// argmax returns the enabled arm with the maximal score, or -1 if no arms are
+// enabled. Ties are broken by the lowest index.
+func (b *banditArms) argmax(score func(arm int) float64) int {
+	idx := -1
+	max := math.Inf(-1)
+	for _, arm := range b.enabled() {
+		if val := score(arm); val > max || idx < 0 {
+			max = val
+			idx = arm
+		}
+	}
+	return idx
+}
+
+// sample selects an enabled arm with probability proportional to its weight,
+// returning -1 if no arms are enabled.
+func (b *banditArms) sample(weight func(arm int) float64) int {
+	enabled := b.enabled()
+	if len(enabled) == 0 {
+		return -1
+	}
+
+	weights := make([]float64, len(enabled))
+	total := 0.0
+	for i, arm := range enabled {
+		weights[i] = weight(arm)
+		total += weights[i]
+	}
+
+	threshold := rand.Float64() * total
+	for i, w := range weights {
+		threshold -= w
+		if threshold < 0 {
+			return enabled[i]
+		}
+	}
+	return enabled[len(enabled)-1]
+}
+
+// enabled returns the indices of the arms that can be selected.
+func (b *banditArms) enabled() []int {
+	arms := make([]int, 0, len(b.disabled))
+	for i, disabled := range b.disabled {
+		if !disabled {
+			arms = append(arms, i)
+		}
+	}
+	return arms
+}
+
+//===========================================================================
+// Epsilon Greedy Multi-Armed Bandit
+//===========================================================================
+
+// EpsilonGreedy implements a reinforcement learning strategy such that the
+// maximizing value is selected with probability epsilon and a uniform random
+// selection is made with probability 1-epsilon.
+type EpsilonGreedy struct {
+	banditArms
+	Epsilon float64 // Probability of selecting maximizing value
+}
+
+// Select the arm with the maximizing value with probability epsilon,
+// otherwise uniform random selection of all arms with probability 1-epsilon.
+func (b *EpsilonGreedy) Select() int {
+	if rand.Float64() > b.Epsilon {
+		// Select the maximal value from values.
+		return b.greedy()
+	}
+
+	// Otherwise return any of the values
+	return b.random()
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *EpsilonGreedy) Serialize() interface{} {
+	data := b.serialize("epsilon greedy")
+	data["epsilon"] = b.Epsilon
 	return data
 }
 
@@ -108,17 +542,7 @@ func (b *EpsilonGreedy) Serialize() interface{} {
 // to an exploring learning strategy at start and prefering exploitation as
 // more selections are made.
 type AnnealingEpsilonGreedy struct {
-	counts  []uint64       // Number of times each index was selected
-	values  []float64      // Reward values condition by frequency
-	history *BanditHistory // History of reward values per iteration
-}
-
-// Init the bandit with nArms number of possible choices, which are referred
-// to by index in both the Counts and Values arrays.
-func (b *AnnealingEpsilonGreedy) Init(nArms int) {
-	b.counts = make([]uint64, nArms, nArms)
-	b.values = make([]float64, nArms, nArms)
-	b.history = NewBanditHistory()
+	banditArms
 }
 
 // Epsilon is computed by the current number of trials such that the more
@@ -139,137 +563,664 @@ func (b *AnnealingEpsilonGreedy) Epsilon() float64 {
 func (b *AnnealingEpsilonGreedy) Select() int {
 	if rand.Float64() > b.Epsilon() {
 		// Select the maximal value from values.
-		max := -1.0
-		idx := -1
-
-		// Find the index of the maximal value.
-		for i, val := range b.values {
-			if val > max {
-				max = val
-				idx = i
-			}
+		return b.greedy()
+	}
+
+	// Otherwise return any of the values
+	return b.random()
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *AnnealingEpsilonGreedy) Serialize() interface{} {
+	data := b.serialize("annealing epsilon greedy")
+	data["epsilon"] = b.Epsilon()
+	return data
+}
+
+//===========================================================================
+// Uniform strategy
+//===========================================================================
+
+// Uniform selects all values with an equal likelihood on every selection.
+// While it tracks the frequency of selection and the reward costs, this
+// information does not affect the way it selects values.
+type Uniform struct {
+	banditArms
+}
+
+// Select the arm with equal probability for each choice.
+func (b *Uniform) Select() int {
+	return b.random()
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *Uniform) Serialize() interface{} {
+	return b.serialize("uniform selection")
+}
+
+//===========================================================================
+// UCB1 Multi-Armed Bandit
+//===========================================================================
+
+// UCB1 implements the upper confidence bound strategy, selecting the arm that
+// maximizes the sum of its value and an exploration bonus that shrinks as the
+// arm is selected more frequently relative to the total number of trials.
+// Every arm is selected once before the confidence bounds are used. With a
+// discount factor this is discounted UCB and with a window it is sliding
+// window UCB: arms that have not been selected recently have a larger bonus
+// so that they are explored again after network conditions change.
+type UCB1 struct {
+	banditArms
+}
+
+// Select the arm with the maximal upper confidence bound.
+func (b *UCB1) Select() int {
+	t := b.trials()
+	return b.argmax(func(arm int) float64 {
+		n := b.selections(arm)
+		if n == 0 {
+			return math.Inf(1)
 		}
 
-		return idx
+		return b.values[arm] + math.Sqrt(2*math.Log(t)/n)
+	})
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *UCB1) Serialize() interface{} {
+	switch {
+	case b.discount > 0:
+		return b.serialize("discounted ucb")
+	case b.window > 0:
+		return b.serialize("sliding window ucb")
+	default:
+		return b.serialize("ucb1")
 	}
+}
 
-	// Otherwise return any of the values
-	return rand.Intn(len(b.values))
+//===========================================================================
+// Bayesian UCB Multi-Armed Bandit
+//===========================================================================
+
+// BayesianUCB implements an upper confidence bound strategy using a Gaussian
+// posterior of each arm's reward, selecting the arm whose mean plus the
+// specified number of posterior standard deviations is maximal. Arms with
+// fewer than two selections have unit variance.
+type BayesianUCB struct {
+	banditArms
+	Confidence float64 // Number of standard deviations of the upper bound
 }
 
-// Update the selected arm with the reward so that the strategy can learn the
-// maximizing value (conditioned by the frequency of selection).
-func (b *AnnealingEpsilonGreedy) Update(arm int, reward float64) {
-	// Update the frequency
-	b.counts[arm]++
-	n := float64(b.counts[arm])
+// Select the arm with the maximal posterior upper bound.
+func (b *BayesianUCB) Select() int {
+	return b.argmax(func(arm int) float64 {
+		n := b.selections(arm)
+		if n < 2 {
+			return b.values[arm] + b.Confidence/math.Sqrt(n+1)
+		}
+		return b.values[arm] + b.Confidence*math.Sqrt(b.variance(arm)/n)
+	})
+}
 
-	value := b.values[arm]
-	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+// Serialize the bandit strategy to dump to JSON.
+func (b *BayesianUCB) Serialize() interface{} {
+	data := b.serialize("bayesian ucb")
+	data["confidence"] = b.Confidence
+	return data
+}
 
-	b.history.Update(arm, reward)
+//===========================================================================
+// Thompson Sampling Multi-Armed Bandits
+//===========================================================================
+
+// BetaThompsonSampling treats rewards as the probability of a successful
+// selection and maintains a Beta posterior for each arm, selecting the arm
+// whose sample from its posterior is maximal. The posterior is derived from
+// the (possibly discounted or windowed) selections and value of the arm with
+// a uniform prior; values are clipped to [0, 1].
+type BetaThompsonSampling struct {
+	banditArms
 }
 
-// Counts returns the frequency each arm was selected
-func (b *AnnealingEpsilonGreedy) Counts() []uint64 {
-	return b.counts
+// Select the arm with the maximal sample from its Beta posterior.
+func (b *BetaThompsonSampling) Select() int {
+	return b.argmax(func(arm int) float64 {
+		return randBeta(b.posterior(arm))
+	})
 }
 
-// Values returns the reward distribution of each arm
-func (b *AnnealingEpsilonGreedy) Values() []float64 {
-	return b.values
+// posterior returns the alpha and beta parameters of the arm's posterior.
+func (b *BetaThompsonSampling) posterior(arm int) (alpha, beta float64) {
+	n := b.selections(arm)
+	p := math.Min(1, math.Max(0, b.values[arm]))
+	return 1 + n*p, 1 + n*(1-p)
 }
 
 // Serialize the bandit strategy to dump to JSON.
-func (b *AnnealingEpsilonGreedy) Serialize() interface{} {
-	data := make(map[string]interface{})
-	data["strategy"] = "annealing epsilon greedy"
-	data["epsilon"] = b.Epsilon()
-	data["counts"] = b.counts
-	data["values"] = b.values
-	data["history"] = b.history
+func (b *BetaThompsonSampling) Serialize() interface{} {
+	alpha := make([]float64, len(b.values))
+	beta := make([]float64, len(b.values))
+	for arm := range b.values {
+		alpha[arm], beta[arm] = b.posterior(arm)
+	}
+
+	data := b.serialize("beta thompson sampling")
+	data["alpha"] = alpha
+	data["beta"] = beta
 	return data
 }
 
+// GaussianThompsonSampling maintains a Gaussian posterior of the mean reward
+// of each arm, selecting the arm whose sample from its posterior is maximal.
+// The posterior of an arm with fewer than two selections has unit variance.
+type GaussianThompsonSampling struct {
+	banditArms
+}
+
+// Select the arm with the maximal sample from its Gaussian posterior.
+func (b *GaussianThompsonSampling) Select() int {
+	return b.argmax(func(arm int) float64 {
+		n := b.selections(arm)
+		if n < 2 {
+			return b.values[arm] + rand.NormFloat64()/math.Sqrt(n+1)
+		}
+		return b.values[arm] + rand.NormFloat64()*math.Sqrt(b.variance(arm)/n)
+	})
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *GaussianThompsonSampling) Serialize() interface{} {
+	return b.serialize("gaussian thompson sampling")
+}
+
 //===========================================================================
-// Uniform strategy
+// Softmax (Boltzmann) Multi-Armed Bandit
 //===========================================================================
 
-// Uniform selects all values with an equal likelihood on every selection.
-// While it tracks the frequency of selection and the reward costs, this
-// information does not affect the way it selects values.
-type Uniform struct {
-	counts  []uint64       // Number of times each index was selected
-	values  []float64      // Reward values condition by frequency
-	history *BanditHistory // History of reward values per iteration
+// Softmax selects each arm with probability proportional to the exponent of
+// its value divided by the temperature. High temperatures tend toward uniform
+// selection and low temperatures tend toward greedy selection.
+type Softmax struct {
+	banditArms
+	Temperature float64 // The temperature of the Boltzmann distribution
 }
 
-// Init the bandit with nArms number of possible choices, which are referred
-// to by index in both the Counts and Values arrays.
-func (b *Uniform) Init(nArms int) {
-	b.counts = make([]uint64, nArms, nArms)
-	b.values = make([]float64, nArms, nArms)
-	b.history = NewBanditHistory()
+// Select an arm from the Boltzmann distribution of the values.
+func (b *Softmax) Select() int {
+	// Subtract the maximal value for numerical stability
+	max := b.argmax(func(arm int) float64 { return b.values[arm] })
+	if max < 0 {
+		return -1
+	}
+
+	return b.sample(func(arm int) float64 {
+		return math.Exp((b.values[arm] - b.values[max]) / b.Temperature)
+	})
 }
 
-// Select the arm with equal probability for each choice.
-func (b *Uniform) Select() int {
-	return rand.Intn(len(b.values))
+// Serialize the bandit strategy to dump to JSON.
+func (b *Softmax) Serialize() interface{} {
+	data := b.serialize("softmax")
+	data["temperature"] = b.Temperature
+	return data
 }
 
-// Update the selected arm with the reward so that the strategy can learn the
-// maximizing value (conditioned by the frequency of selection).
-func (b *Uniform) Update(arm int, reward float64) {
-	// Update the frequency
-	b.counts[arm]++
-	n := float64(b.counts[arm])
+//===========================================================================
+// Exp3 Multi-Armed Bandit
+//===========================================================================
 
-	value := b.values[arm]
-	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+// Exp3 implements the exponential-weight algorithm for exploration and
+// exploitation, which makes no stochastic assumptions about the rewards and
+// is therefore suited to adversarial or changing network conditions. Each arm
+// is selected with a mixture of its weight and uniform exploration gamma;
+// rewards are clipped to [0, 1].
+type Exp3 struct {
+	banditArms
+	Gamma      float64   // The rate of uniform exploration in (0, 1]
+	expWeights []float64 // The exponential weights of each arm
+}
 
-	b.history.Update(arm, reward)
+// Init the bandit with nArms number of possible choices.
+func (b *Exp3) Init(nArms int) {
+	b.banditArms.Init(nArms)
+	b.expWeights = make([]float64, nArms, nArms)
+	for i := range b.expWeights {
+		b.expWeights[i] = 1
+	}
 }
 
-// Counts returns the frequency each arm was selected
-func (b *Uniform) Counts() []uint64 {
-	return b.counts
+// Select an arm with the Exp3 probability distribution.
+func (b *Exp3) Select() int {
+	return b.sample(b.probability)
 }
 
-// Values returns the reward distribution of each arm
-func (b *Uniform) Values() []float64 {
-	return b.values
+// Update the weight of the selected arm with the importance weighted reward.
+func (b *Exp3) Update(arm int, reward float64) {
+	b.banditArms.Update(arm, reward)
+
+	// The arm may have been disabled during the session, in which case its
+	// probability is undefined and its weight is not updated.
+	if b.disabled[arm] {
+		return
+	}
+
+	k := float64(len(b.enabled()))
+	estimate := math.Min(1, math.Max(0, reward)) / b.probability(arm)
+	b.expWeights[arm] *= math.Exp(b.Gamma * estimate / k)
+
+	// Normalize the weights to prevent overflow
+	max := 0.0
+	for _, w := range b.expWeights {
+		max = math.Max(max, w)
+	}
+	for i := range b.expWeights {
+		b.expWeights[i] /= max
+	}
+}
+
+// Add a new arm with the maximal weight so that it is explored, returning its
+// index.
+func (b *Exp3) Add() int {
+	b.expWeights = append(b.expWeights, 1)
+	return b.banditArms.Add()
+}
+
+// Remove the arm at the specified index.
+func (b *Exp3) Remove(arm int) {
+	b.expWeights = append(b.expWeights[:arm], b.expWeights[arm+1:]...)
+	b.banditArms.Remove(arm)
+}
+
+// checkpoint the state of the arm along with its exponential weight.
+func (b *Exp3) checkpoint(arm int, state *ArmCheckpoint) {
+	b.banditArms.checkpoint(arm, state)
+	state.ExpWeight = b.expWeights[arm]
+}
+
+// restore the state of the arm along with its exponential weight, which is
+// raised to the power of the decay factor so that it tends to the uninformed
+// weight as the decay approaches zero.
+func (b *Exp3) restore(arm int, state ArmCheckpoint, decay float64) {
+	b.banditArms.restore(arm, state, decay)
+	if state.ExpWeight > 0 {
+		b.expWeights[arm] = math.Pow(state.ExpWeight, decay)
+	}
+}
+
+// probability of selecting the arm from the enabled arms.
+func (b *Exp3) probability(arm int) float64 {
+	enabled := b.enabled()
+	total := 0.0
+	for _, i := range enabled {
+		total += b.expWeights[i]
+	}
+
+	k := float64(len(enabled))
+	return (1-b.Gamma)*(b.expWeights[arm]/total) + b.Gamma/k
 }
 
 // Serialize the bandit strategy to dump to JSON.
-func (b *Uniform) Serialize() interface{} {
-	data := make(map[string]interface{})
-	data["strategy"] = "uniform selection"
-	data["counts"] = b.counts
-	data["values"] = b.values
-	data["history"] = b.history
+func (b *Exp3) Serialize() interface{} {
+	data := b.serialize("exp3")
+	data["gamma"] = b.Gamma
+	data["weights"] = b.expWeights
 	return data
 }
 
+//===========================================================================
+// LinUCB Contextual Multi-Armed Bandit
+//===========================================================================
+
+// ContextualBandit is a BanditStrategy whose selection is conditioned on a
+// context of features observed for each arm before every selection.
+type ContextualBandit interface {
+	BanditStrategy
+	Context(arm int, features []float64) // Set the features of the arm for the next selection
+}
+
+// LinUCB implements the disjoint LinUCB contextual bandit: the reward of each
+// arm is modeled as a linear function of its features, learned by ridge
+// regression, and the arm with the maximal upper confidence bound of the
+// predicted reward is selected. The arms are updated with the features they
+// had when they were last selected. If the context of an arm has not been
+// set, only the bias feature is used.
+type LinUCB struct {
+	banditArms
+	Alpha    float64       // Number of standard deviations of the upper bound
+	Features []string      // Names of the features, the first is the bias
+	context  [][]float64   // Features of each arm for the next selection
+	selected [][]float64   // Features of each arm when it was last selected
+	inverse  [][][]float64 // Inverse of the design matrix of each arm
+	response [][]float64   // Sum of the rewarded features of each arm
+}
+
+// Init the bandit with nArms number of possible choices.
+func (b *LinUCB) Init(nArms int) {
+	b.banditArms.Init(nArms)
+	b.context = nil
+	b.selected = nil
+	b.inverse = nil
+	b.response = nil
+
+	for i := 0; i < nArms; i++ {
+		b.add()
+	}
+}
+
+// Context sets the features of the arm that are used for the next selection.
+func (b *LinUCB) Context(arm int, features []float64) {
+	copy(b.context[arm], features)
+}
+
+// Select the arm with the maximal upper confidence bound of the reward
+// predicted by its features.
+func (b *LinUCB) Select() int {
+	arm := b.argmax(func(arm int) float64 {
+		x := b.context[arm]
+		ax := mulvec(b.inverse[arm], x)
+		return dot(ax, b.response[arm]) + b.Alpha*math.Sqrt(math.Max(0, dot(x, ax)))
+	})
+
+	if arm >= 0 {
+		copy(b.selected[arm], b.context[arm])
+	}
+	return arm
+}
+
+// Update the model of the selected arm with the reward using the features of
+// the arm when it was selected. The inverse of the design matrix is updated
+// with the Sherman-Morrison formula.
+func (b *LinUCB) Update(arm int, reward float64) {
+	b.banditArms.Update(arm, reward)
+
+	x := b.selected[arm]
+	inv := b.inverse[arm]
+	ax := mulvec(inv, x)
+	denom := 1 + dot(x, ax)
+	for i := range inv {
+		for j := range inv[i] {
+			inv[i][j] -= ax[i] * ax[j] / denom
+		}
+	}
+
+	for i, xi := range x {
+		b.response[arm][i] += reward * xi
+	}
+}
+
+// Add a new arm with an uninformed model and return its index.
+func (b *LinUCB) Add() int {
+	b.add()
+	return b.banditArms.Add()
+}
+
+// Remove the arm at the specified index.
+func (b *LinUCB) Remove(arm int) {
+	b.context = append(b.context[:arm], b.context[arm+1:]...)
+	b.selected = append(b.selected[:arm], b.selected[arm+1:]...)
+	b.inverse = append(b.inverse[:arm], b.inverse[arm+1:]...)
+	b.response = append(b.response[:arm], b.response[arm+1:]...)
+	b.banditArms.Remove(arm)
+}
+
+// Weights returns the learned coefficients of the features for each arm.
+func (b *LinUCB) Weights() [][]float64 {
+	weights := make([][]float64, len(b.inverse))
+	for arm := range b.inverse {
+		weights[arm] = mulvec(b.inverse[arm], b.response[arm])
+	}
+	return weights
+}
+
+// checkpoint the state of the arm along with its ridge regression model.
+func (b *LinUCB) checkpoint(arm int, state *ArmCheckpoint) {
+	b.banditArms.checkpoint(arm, state)
+
+	state.Inverse = make([][]float64, len(b.inverse[arm]))
+	for i, row := range b.inverse[arm] {
+		state.Inverse[i] = append([]float64(nil), row...)
+	}
+	state.Response = append([]float64(nil), b.response[arm]...)
+}
+
+// restore the state of the arm along with its ridge regression model if it
+// has the same features. The decay factor interpolates the design matrix and
+// the response of the model with those of an uninformed model, so that a
+// decay of zero forgets what the model has learned.
+func (b *LinUCB) restore(arm int, state ArmCheckpoint, decay float64) {
+	b.banditArms.restore(arm, state, decay)
+
+	d := len(b.Features)
+	if len(state.Inverse) != d || len(state.Response) != d {
+		return
+	}
+
+	inverse := make([][]float64, d)
+	for i, row := range state.Inverse {
+		if len(row) != d {
+			return
+		}
+		inverse[i] = append([]float64(nil), row...)
+	}
+
+	response := make([]float64, d)
+	for i, r := range state.Response {
+		response[i] = decay * r
+	}
+
+	if decay < 1 {
+		design, ok := invert(inverse)
+		if !ok {
+			return
+		}
+
+		for i := range design {
+			for j := range design[i] {
+				design[i][j] *= decay
+			}
+			design[i][i] += 1 - decay
+		}
+
+		if inverse, ok = invert(design); !ok {
+			return
+		}
+	}
+
+	b.inverse[arm] = inverse
+	b.response[arm] = response
+}
+
+// add the model of a new arm with the identity design matrix.
+func (b *LinUCB) add() {
+	d := len(b.Features)
+	inv := make([][]float64, d)
+	for i := range inv {
+		inv[i] = make([]float64, d)
+		inv[i][i] = 1
+	}
+
+	// Only the bias feature is set until the context is given
+	context := make([]float64, d)
+	context[0] = 1
+
+	b.context = append(b.context, context)
+	b.selected = append(b.selected, append([]float64(nil), context...))
+	b.inverse = append(b.inverse, inv)
+	b.response = append(b.response, make([]float64, d))
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *LinUCB) Serialize() interface{} {
+	data := b.serialize("linucb")
+	data["alpha"] = b.Alpha
+	data["features"] = b.Features
+	data["weights"] = b.Weights()
+	return data
+}
+
+// dot returns the dot product of two vectors.
+func dot(x, y []float64) float64 {
+	sum := 0.0
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
+
+// invert returns the inverse of a square matrix by Gauss-Jordan elimination
+// with partial pivoting, or false if the matrix is singular.
+func invert(m [][]float64) ([][]float64, bool) {
+	n := len(m)
+	a := make([][]float64, n)
+	inv := make([][]float64, n)
+	for i := range m {
+		a[i] = append([]float64(nil), m[i]...)
+		inv[i] = make([]float64, n)
+		inv[i][i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+
+		a[col], a[pivot] = a[pivot], a[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		scale := a[col][col]
+		for j := 0; j < n; j++ {
+			a[col][j] /= scale
+			inv[col][j] /= scale
+		}
+
+		for row := 0; row < n; row++ {
+			if row == col || a[row][col] == 0 {
+				continue
+			}
+
+			factor := a[row][col]
+			for j := 0; j < n; j++ {
+				a[row][j] -= factor * a[col][j]
+				inv[row][j] -= factor * inv[col][j]
+			}
+		}
+	}
+
+	return inv, true
+}
+
+// mulvec returns the product of a square matrix and a vector.
+func mulvec(m [][]float64, x []float64) []float64 {
+	y := make([]float64, len(m))
+	for i, row := range m {
+		y[i] = dot(row, x)
+	}
+	return y
+}
+
+//===========================================================================
+// Random Variates
+//===========================================================================
+
+// randGamma returns a sample from the Gamma distribution with the specified
+// shape and unit scale using the Marsaglia and Tsang method.
+func randGamma(shape float64) float64 {
+	if shape < 1 {
+		// Boost the shape and correct the sample for shape < 1
+		return randGamma(shape+1) * math.Pow(rand.Float64(), 1/shape)
+	}
+
+	d := shape - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rand.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+
+		v = v * v * v
+		u := rand.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+// randBeta returns a sample from the Beta distribution with parameters a, b.
+func randBeta(a, b float64) float64 {
+	x := randGamma(a)
+	y := randGamma(b)
+	return x / (x + y)
+}
+
 //===========================================================================
 // Bandity History - For Experimentation
 //===========================================================================
 
-// NewBanditHistory creates and returns a bandit history struct.
-func NewBanditHistory() *BanditHistory {
+// BanditHistorySize is the default number of recent rewards kept in memory.
+const BanditHistorySize = 1000
+
+// NewBanditHistory creates and returns a bandit history that keeps the most
+// recent size rewards, or BanditHistorySize if size is not positive.
+func NewBanditHistory(size int) *BanditHistory {
+	if size <= 0 {
+		size = BanditHistorySize
+	}
+
 	history := new(BanditHistory)
-	history.Arms = make([]int, 0, 10000)
-	history.Rewards = make([]float64, 0, 10000)
+	history.arms = make([]int, 0, size)
+	history.rewards = make([]float64, 0, size)
 	return history
 }
 
-// BanditHistory tracks the selected arms and their rewards over time.
+// BanditHistory tracks the most recent selected arms and their rewards in a
+// ring buffer so that memory is bounded in long running experiments; the
+// complete history can be streamed to disk with a BanditLogger.
 type BanditHistory struct {
-	Arms    []int     `json:"arms"`    // selected arms per iteration
-	Rewards []float64 `json:"rewards"` // reward values per iteration
+	arms    []int     // selected arms per iteration
+	rewards []float64 // reward values per iteration
+	next    int       // index of the oldest iteration once the buffer is full
+	total   uint64    // total number of iterations, including evicted ones
 }
 
-// Update the history
+// Update the history, evicting the oldest iteration if the buffer is full.
 func (h *BanditHistory) Update(arm int, reward float64) {
-	h.Arms = append(h.Arms, arm)
-	h.Rewards = append(h.Rewards, reward)
+	h.total++
+	if len(h.arms) < cap(h.arms) {
+		h.arms = append(h.arms, arm)
+		h.rewards = append(h.rewards, reward)
+		return
+	}
+
+	h.arms[h.next] = arm
+	h.rewards[h.next] = reward
+	h.next = (h.next + 1) % len(h.arms)
+}
+
+// Arms returns the selected arms from the oldest to most recent iteration.
+func (h *BanditHistory) Arms() []int {
+	return append(append(make([]int, 0, len(h.arms)), h.arms[h.next:]...), h.arms[:h.next]...)
+}
+
+// Rewards returns the rewards from the oldest to most recent iteration.
+func (h *BanditHistory) Rewards() []float64 {
+	return append(append(make([]float64, 0, len(h.rewards)), h.rewards[h.next:]...), h.rewards[:h.next]...)
+}
+
+// MarshalJSON serializes the iterations in the buffer in order along with
+// the total number of iterations.
+func (h *BanditHistory) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"arms":    h.Arms(),
+		"rewards": h.Rewards(),
+		"total":   h.total,
+	})
 }
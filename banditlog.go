@@ -0,0 +1,123 @@
+package honu
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// BanditLogBufferSize describes the maximum number of async bandit log
+// statements before the caller will have to block.
+const BanditLogBufferSize = 10000
+
+// NewBanditLogger creates a logger that streams the bandit history to the
+// JSON Lines file at the path.
+func NewBanditLogger(path string) (*BanditLogger, error) {
+	out, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	bl := &BanditLogger{
+		file: out,
+		err:  nil,
+		msgs: make(chan *banditMessage, BanditLogBufferSize),
+		done: make(chan bool),
+	}
+
+	go bl.flusher()
+	return bl, nil
+}
+
+// BanditLogger records every peer selection and its reward on disk as it
+// happens, so that the complete history of long running experiments does
+// not have to be kept in memory. It uses an asynchronous writer so it
+// doesn't block anti-entropy. Rewards logged after it is closed are dropped.
+type BanditLogger struct {
+	sync.RWMutex
+	file   *os.File
+	err    error
+	msgs   chan *banditMessage
+	done   chan bool
+	closed bool
+}
+
+// simple data structure for storing a bandit iteration.
+type banditMessage struct {
+	Timestamp time.Time `json:"timestamp"` // When the reward was computed
+	Peer      string    `json:"peer"`      // The address of the selected peer
+	Arm       int       `json:"arm"`       // The index of the selected arm
+	Reward    float64   `json:"reward"`    // The reward of the anti-entropy session
+	Latency   float64   `json:"latency"`   // The latency of the session in seconds
+	Items     uint64    `json:"items"`     // The number of versions exchanged
+}
+
+// Log the reward of an anti-entropy session with the peer.
+func (l *BanditLogger) Log(arm int, reward float64, session *Synchronization) {
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.closed {
+		return
+	}
+
+	l.msgs <- &banditMessage{
+		Timestamp: time.Now(),
+		Peer:      session.Peer,
+		Arm:       arm,
+		Reward:    reward,
+		Latency:   session.Latency().Seconds(),
+		Items:     session.Items(),
+	}
+}
+
+// Close the logger and wait until it's done writing all buffered messages.
+func (l *BanditLogger) Close() error {
+	l.Lock()
+	l.closed = true
+	close(l.msgs)
+	l.Unlock()
+	<-l.done
+
+	if l.err != nil {
+		return l.err
+	}
+
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+
+	return l.file.Close()
+}
+
+// Error returns any issues the bandit logger had
+func (l *BanditLogger) Error() error {
+	return l.err
+}
+
+// routine that reads bandit log messages off the msgs channel and writes
+// them to disk. Unlike visibility, the file is not synced on every message;
+// it is synced when the logger is closed.
+func (l *BanditLogger) flusher() {
+	defer func() { l.done <- true }()
+
+	for msg := range l.msgs {
+		var data []byte
+
+		if data, l.err = json.Marshal(msg); l.err != nil {
+			warne(l.err)
+			break
+		}
+
+		data = append(data, byte('\n'))
+		if _, l.err = l.file.Write(data); l.err != nil {
+			warne(l.err)
+			break
+		}
+	}
+
+	// Drain the remaining messages so that callers do not block
+	for range l.msgs {
+	}
+}
@@ -8,15 +8,16 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	pb "github.com/bbengfort/honu/rpc"
-	"github.com/bbengfort/x/stats"
 )
 
 // Benchmark runs clients sending continuous accesses to the remote server.
 type Benchmark struct {
-	workers int
-	clients []*Client
-	extra   map[string]interface{}
-	metrics *stats.Benchmark
+	workers   int
+	clients   []*Client
+	extra     map[string]interface{}
+	metrics   *Histogram
+	reporter  *IntervalReporter
+	latencies *Latencies
 }
 
 // NewBenchmark creates the data structure and clients.
@@ -48,6 +49,21 @@ func NewBenchmark(workers int, prefix string, visibility bool, extra map[string]
 	return b, nil
 }
 
+// Report appends a record of the throughput and latency percentiles of the
+// clients to the JSON Lines file at the path every interval while the
+// benchmark is running.
+func (b *Benchmark) Report(path string, interval time.Duration) (err error) {
+	b.latencies = new(Latencies)
+	if b.reporter, err = NewIntervalReporter(path, interval, b.collectInterval); err != nil {
+		return err
+	}
+
+	for _, client := range b.clients {
+		client.latencies = b.latencies
+	}
+	return nil
+}
+
 // Run the benchmark with the specified duration
 func (b *Benchmark) Run(addr, outpath string, duration, delay, rate time.Duration) error {
 	if delay > 0 {
@@ -57,13 +73,24 @@ func (b *Benchmark) Run(addr, outpath string, duration, delay, rate time.Duratio
 
 	status("starting throughput benchmark with %d clients for %s", b.workers, duration)
 
+	if b.reporter != nil {
+		b.reporter.Start()
+	}
+
 	group := new(errgroup.Group)
 	for _, client := range b.clients {
 		c := client
 		group.Go(func() error { return c.Run(addr, duration, rate) })
 	}
 
-	if err := group.Wait(); err != nil {
+	err := group.Wait()
+	if b.reporter != nil {
+		if rerr := b.reporter.Stop(); rerr != nil {
+			warne(rerr)
+		}
+	}
+
+	if err != nil {
 		return err
 	}
 
@@ -78,7 +105,7 @@ func (b *Benchmark) Run(addr, outpath string, duration, delay, rate time.Duratio
 // Results writes the results to disk.
 func (b *Benchmark) Results(path string) error {
 	latencies := make([]float64, 0)
-	b.metrics = new(stats.Benchmark)
+	b.metrics = new(Histogram)
 	for _, client := range b.clients {
 		b.metrics.Append(client.metrics)
 	}
@@ -99,6 +126,25 @@ func (b *Benchmark) Results(path string) error {
 	return nil
 }
 
+// collectInterval returns the throughput and latencies of the clients
+// during the interval.
+func (b *Benchmark) collectInterval(elapsed time.Duration) map[string]interface{} {
+	latency := b.latencies.Summary()
+	accesses := latency["n"].(int)
+
+	var throughput float64
+	if elapsed > 0 {
+		throughput = float64(accesses) / elapsed.Seconds()
+	}
+
+	data := make(map[string]interface{})
+	data["accesses"] = accesses
+	data["throughput"] = throughput
+	data["latency"] = latency
+	data["workers"] = b.workers
+	return data
+}
+
 // String returns the metrics results
 func (b *Benchmark) String() string {
 	return fmt.Sprintf(
@@ -146,6 +192,9 @@ func (c *Client) Access(done chan<- bool, echan chan<- error, rate time.Duration
 	if rep.Success {
 		delta := time.Since(start)
 		c.metrics.Update(delta)
+		if c.latencies != nil {
+			c.latencies.Update(delta)
+		}
 		return
 	}
 
@@ -160,7 +209,7 @@ func (c *Client) Access(done chan<- bool, echan chan<- error, rate time.Duration
 
 // Run a continuous access client for the specified duration.
 func (c *Client) Run(addr string, duration, rate time.Duration) error {
-	c.metrics = new(stats.Benchmark)
+	c.metrics = new(Histogram)
 
 	if err := c.Connect(addr); err != nil {
 		return err
@@ -0,0 +1,131 @@
+package honu
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	pb "github.com/bbengfort/honu/rpc"
+	"golang.org/x/net/context"
+)
+
+// BootstrapFrom sets the peer that a new replica loads a snapshot of the
+// namespace from before it starts serving requests and anti-entropy.
+func (s *Server) BootstrapFrom(peer string) {
+	s.bootstrap = peer
+}
+
+// bootstrapFrom streams a consistent snapshot of the namespace of the peer
+// and loads it into the store, then synchronizes with the peer over a sync
+// stream so that writes that arrived at the peer while the snapshot was
+// streaming are not lost. Bootstrapping is cancelled with the context.
+func (s *Server) bootstrapFrom(ctx context.Context, peer string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "bootstrap")
+	span.SetAttribute("peer", peer)
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+
+	start := time.Now()
+	conn, err := s.dial(ctx, peer)
+	if err != nil {
+		return fmt.Errorf("could not connect to bootstrap peer %s: %s", peer, err)
+	}
+	defer conn.Close()
+
+	req := &pb.SnapshotRequest{Cluster: s.cluster, Limit: s.limit}
+	if s.codec != nil {
+		req.Encoding = s.codec.Name()
+	}
+
+	client := pb.NewGossipClient(conn)
+	stream, err := client.Snapshot(ctx, req)
+	if err != nil {
+		return fmt.Errorf("could not request snapshot from %s: %s", peer, err)
+	}
+
+	// Load the chunks of the snapshot until the peer closes the stream
+	var keys, loaded uint64
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not bootstrap from %s: %s", peer, err)
+		}
+
+		if chunk.Keys > 0 {
+			keys = chunk.Keys
+		}
+
+		for key, pbentry := range chunk.Entries {
+			if _, ok := s.accept(ctx, key, pbentry); ok {
+				loaded++
+			}
+		}
+	}
+
+	info("loaded %d of %d keys in snapshot from %s in %s", loaded, keys, peer, time.Since(start))
+	span.SetAttribute("keys", loaded)
+
+	// Catch up with the writes to the peer during the snapshot
+	session := &Synchronization{Peer: peer}
+	if err = s.syncStream(ctx, client, s.store.View(), 0, session, newSyncStats()); err != nil {
+		return fmt.Errorf("could not synchronize with bootstrap peer %s: %s", peer, err)
+	}
+
+	status("bootstrapped %d keys from %s in %s", s.store.Length(), peer, time.Since(start))
+	return nil
+}
+
+//===========================================================================
+// Server Bootstrap RPC methods
+//===========================================================================
+
+// Snapshot streams a consistent copy of the namespace to a new replica in
+// chunks of entries within the requested limit. Writes that arrive while the
+// snapshot is streaming are not included; they are synchronized afterward.
+func (s *Server) Snapshot(in *pb.SnapshotRequest, stream pb.Gossip_SnapshotServer) error {
+	if in.Cluster != s.cluster {
+		return fmt.Errorf("replica is in cluster %q not %q", s.cluster, in.Cluster)
+	}
+
+	// Compress values with the requested codec if it's known
+	codec, err := NewCodec(in.Encoding)
+	if err != nil {
+		debug("sending uncompressed snapshot: %s", err)
+	}
+
+	entries := s.store.Entries()
+	chunk := &pb.SnapshotChunk{
+		Entries: make(map[string]*pb.Entry),
+		Keys:    uint64(len(entries)),
+	}
+
+	var size uint64
+	for key, entry := range entries {
+		pbentry := encodeEntry(entry, codec)
+		n := entrySize(key, pbentry)
+
+		if in.Limit > 0 && len(chunk.Entries) > 0 && size+n > in.Limit {
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+
+			chunk = &pb.SnapshotChunk{Entries: make(map[string]*pb.Entry)}
+			size = 0
+		}
+
+		chunk.Entries[key] = pbentry
+		size += n
+	}
+
+	if err := stream.Send(chunk); err != nil {
+		return err
+	}
+
+	info("streamed snapshot of %d keys", len(entries))
+	return nil
+}
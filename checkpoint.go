@@ -0,0 +1,280 @@
+package honu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//===========================================================================
+// Bandit Checkpoints
+//===========================================================================
+
+// BanditCheckpoint is the learned state of the bandit that is saved to disk
+// so that a restarted server can warm-start peer selection. The arms are
+// identified by the peer rather than by index, since the order of the peers
+// changes as replicas join and leave the cluster.
+type BanditCheckpoint struct {
+	Strategy  string          `json:"strategy"`  // The name of the strategy that learned the state
+	Options   BanditOptions   `json:"options"`   // The parameters of the strategy that learned the state
+	Timestamp time.Time       `json:"timestamp"` // When the checkpoint was taken
+	Arms      []ArmCheckpoint `json:"arms"`      // The learned state of each peer
+}
+
+// ArmCheckpoint is the learned state of the bandit arm of a peer. The state
+// that only some strategies or reward estimates learn is omitted otherwise.
+type ArmCheckpoint struct {
+	Peer      string      `json:"peer"`                // The address of the peer
+	PID       uint64      `json:"pid"`                 // The process id of the peer (zero if unknown)
+	Count     uint64      `json:"count"`               // The number of times the peer was selected
+	Value     float64     `json:"value"`               // The estimated reward of the peer
+	Square    float64     `json:"square"`              // The estimated squared reward of the peer
+	Weight    float64     `json:"weight,omitempty"`    // The discounted or windowed number of selections
+	Sum       float64     `json:"sum,omitempty"`       // The discounted or windowed sum of rewards
+	SumSq     float64     `json:"sumsq,omitempty"`     // The discounted or windowed sum of squared rewards
+	Recent    []float64   `json:"recent,omitempty"`    // The rewards of the peer in the sliding window, oldest first
+	ExpWeight float64     `json:"expWeight,omitempty"` // The exponential weight of the peer (exp3)
+	Inverse   [][]float64 `json:"inverse,omitempty"`   // The inverse of the design matrix of the peer (linucb)
+	Response  []float64   `json:"response,omitempty"`  // The sum of the rewarded features of the peer (linucb)
+}
+
+// checkpointer periodically saves the state of the bandit and holds the
+// loaded state of peers that have not yet been restored.
+type checkpointer struct {
+	path     string                   // Path of the checkpoint file
+	interval time.Duration            // Delay between periodic checkpoints (disabled if zero)
+	decay    float64                  // Factor the loaded confidence is multiplied by
+	pending  map[string]ArmCheckpoint // Loaded arms that have not been restored, keyed by identity
+}
+
+// Checkpoint the bandit to the file at the specified path on shutdown and
+// every interval, if the interval is greater than zero. If the file exists,
+// the bandit is warm-started from it: the counts, estimates and strategy
+// state of each arm (e.g. the weights of exp3 or the model of linucb) are
+// restored, and the confidence in them is reduced by the decay factor in
+// [0, 1]. Peers are identified by process id if known, otherwise by address.
+// If the checkpoint was taken by the same strategy, its parameters replace
+// those the strategy was created with so that the restored state is used as
+// it was learned. Must be called after Replicate.
+func (s *Server) Checkpoint(path string, interval time.Duration, decay float64) error {
+	if s.bandit == nil {
+		return fmt.Errorf("cannot checkpoint bandit of a server that is not replicating")
+	}
+
+	if decay < 0 || decay > 1 {
+		return fmt.Errorf("checkpoint decay must be in [0, 1], not %f", decay)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.checkpoint = &checkpointer{
+		path:     path,
+		interval: interval,
+		decay:    decay,
+		pending:  make(map[string]ArmCheckpoint),
+	}
+
+	// Load the checkpoint if one exists
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err == nil {
+		checkpoint := new(BanditCheckpoint)
+		if err := json.Unmarshal(data, checkpoint); err != nil {
+			return fmt.Errorf("could not parse bandit checkpoint %s: %s", path, err)
+		}
+
+		strategy := s.bandit.Serialize().(map[string]interface{})["strategy"]
+		if strategy != checkpoint.Strategy {
+			caution("warm-starting %s bandit from %s checkpoint", strategy, checkpoint.Strategy)
+		} else if options := banditOptions(s.bandit); options != checkpoint.Options {
+			if err := setBanditOptions(s.bandit, checkpoint.Options); err != nil {
+				return fmt.Errorf("could not restore parameters of bandit checkpoint %s: %s", path, err)
+			}
+			caution("restored the %s bandit parameters of the checkpoint, which differ from those configured", strategy)
+		}
+
+		for _, arm := range checkpoint.Arms {
+			s.checkpoint.pending[armIdentity(arm.PID, arm.Peer)] = arm
+		}
+
+		// Restore the statically configured peers by address
+		for idx, peer := range s.peers {
+			s.restore(idx, Member{Addr: peer})
+		}
+
+		info(
+			"warm-started bandit from %d peers checkpointed at %s",
+			len(checkpoint.Arms), checkpoint.Timestamp.Format(time.RFC3339),
+		)
+	}
+
+	if interval > 0 {
+		time.AfterFunc(interval, s.checkpointPeriodically)
+	}
+	return nil
+}
+
+// checkpointPeriodically saves the bandit and schedules the next checkpoint.
+func (s *Server) checkpointPeriodically() {
+	// The final checkpoint is saved when the server is shut down
+	if s.isStopped() {
+		return
+	}
+
+	defer time.AfterFunc(s.checkpoint.interval, s.checkpointPeriodically)
+	if err := s.saveCheckpoint(); err != nil {
+		warne(err)
+	}
+}
+
+// saveCheckpoint writes the state of the bandit to the checkpoint file,
+// replacing the previous checkpoint atomically.
+func (s *Server) saveCheckpoint() error {
+	if s.checkpoint == nil {
+		return nil
+	}
+
+	s.Lock()
+	checkpoint := &BanditCheckpoint{
+		Strategy:  fmt.Sprintf("%v", s.bandit.Serialize().(map[string]interface{})["strategy"]),
+		Options:   banditOptions(s.bandit),
+		Timestamp: time.Now(),
+		Arms:      make([]ArmCheckpoint, 0, len(s.peers)),
+	}
+
+	for idx, peer := range s.peers {
+		arm := ArmCheckpoint{Peer: peer}
+		s.bandit.(estimator).checkpoint(idx, &arm)
+
+		if member, ok := s.members.Get(peer); ok {
+			arm.PID = member.PID
+		}
+
+		checkpoint.Arms = append(checkpoint.Arms, arm)
+	}
+	s.Unlock()
+
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename so the checkpoint is never partial
+	tmp, err := ioutil.TempFile(filepath.Dir(s.checkpoint.path), ".checkpoint")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), s.checkpoint.path); err != nil {
+		return err
+	}
+
+	debug("bandit checkpoint of %d peers saved to %s", len(checkpoint.Arms), s.checkpoint.path)
+	return nil
+}
+
+// restore the loaded state of the member, if any, to the bandit arm. The
+// member is matched by process id if known, otherwise by address. Each
+// checkpointed arm is restored at most once. Must be called under lock.
+func (s *Server) restore(idx int, member Member) {
+	if s.checkpoint == nil || len(s.checkpoint.pending) == 0 {
+		return
+	}
+
+	key := armIdentity(member.PID, member.Addr)
+	arm, ok := s.checkpoint.pending[key]
+	if !ok {
+		// Checkpointed arms with a pid may also be matched by address
+		for pkey, parm := range s.checkpoint.pending {
+			if parm.Peer == member.Addr {
+				key, arm, ok = pkey, parm, true
+				break
+			}
+		}
+
+		if !ok {
+			return
+		}
+	}
+
+	delete(s.checkpoint.pending, key)
+	s.bandit.(estimator).restore(idx, arm, s.checkpoint.decay)
+	debug("restored bandit arm of %s with %d selections", member.Addr, s.bandit.Counts()[idx])
+}
+
+// banditOptions returns the parameters of the bandit strategy, excluding the
+// size of the history, which is not learned with.
+func banditOptions(bandit BanditStrategy) BanditOptions {
+	arms := bandit.(estimator).arms()
+	opts := BanditOptions{
+		Decay:    arms.decay,
+		Discount: arms.discount,
+		Window:   arms.window,
+	}
+
+	switch b := bandit.(type) {
+	case *EpsilonGreedy:
+		opts.Epsilon = b.Epsilon
+	case *Softmax:
+		opts.Temperature = b.Temperature
+	case *Exp3:
+		opts.Gamma = b.Gamma
+	case *BayesianUCB:
+		opts.Confidence = b.Confidence
+	case *LinUCB:
+		opts.Confidence = b.Alpha
+	}
+	return opts
+}
+
+// setBanditOptions replaces the parameters of the bandit strategy, which must
+// not have learned anything yet. The size of the history is not replaced
+// since the history has already been created.
+func setBanditOptions(bandit BanditStrategy, opts BanditOptions) error {
+	arms := bandit.(estimator).arms()
+	opts.History = arms.historySize
+	if err := arms.configure(opts); err != nil {
+		return err
+	}
+
+	switch b := bandit.(type) {
+	case *EpsilonGreedy:
+		b.Epsilon = opts.Epsilon
+	case *Softmax:
+		b.Temperature = opts.Temperature
+	case *Exp3:
+		b.Gamma = opts.Gamma
+	case *BayesianUCB:
+		b.Confidence = opts.Confidence
+	case *LinUCB:
+		b.Alpha = opts.Confidence
+	}
+	return nil
+}
+
+// armIdentity returns the key of a checkpointed arm: the pid if it is known,
+// otherwise the address of the peer.
+func armIdentity(pid uint64, addr string) string {
+	if pid > 0 {
+		return fmt.Sprintf("pid:%d", pid)
+	}
+	return fmt.Sprintf("addr:%s", addr)
+}
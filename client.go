@@ -8,7 +8,6 @@ import (
 	"golang.org/x/net/context"
 
 	pb "github.com/bbengfort/honu/rpc"
-	"github.com/bbengfort/x/stats"
 	"google.golang.org/grpc"
 )
 
@@ -23,7 +22,9 @@ type Client struct {
 	addr       string           // the address of the server
 	conn       *grpc.ClientConn // the connection to the server
 	rpc        pb.StorageClient // the transport to make requests on
-	metrics    *stats.Benchmark // client-side latency benchmarks
+	admin      pb.AdminClient   // the transport to make admin requests on
+	metrics    *Histogram       // client-side latency benchmarks
+	latencies  *Latencies       // latencies of the current interval (nil unless reporting)
 	visibility bool             // track put visibility on access
 }
 
@@ -43,6 +44,7 @@ func (c *Client) Connect(addr string) error {
 
 	debug("connected to storage server at %s", c.addr)
 	c.rpc = pb.NewStorageClient(c.conn)
+	c.admin = pb.NewAdminClient(c.conn)
 	return nil
 }
 
@@ -52,7 +54,8 @@ func (c *Client) Close() error {
 		return errors.New("client is not connected, cannot close")
 	}
 
-	c.rpc = nil // nilify the rpc client to the server
+	c.rpc = nil   // nilify the rpc client to the server
+	c.admin = nil // nilify the admin client to the server
 
 	// close the connection
 	if err := c.conn.Close(); err != nil {
@@ -129,3 +132,52 @@ func (c *Client) Put(key string, value []byte, trackVisibility bool) (string, er
 
 	return reply.Version, nil
 }
+
+// Status returns the state of the server, its store and its peers.
+func (c *Client) Status() (*pb.StatusReply, error) {
+	if !c.IsConnected() {
+		return nil, errors.New("not connected, cannot make a request")
+	}
+
+	return c.admin.Status(context.Background(), &pb.StatusRequest{})
+}
+
+// SetLogLevel changes the log level of the server at runtime.
+func (c *Client) SetLogLevel(level string) (string, error) {
+	return c.perform(c.admin.SetLogLevel, &pb.AdminRequest{Level: level})
+}
+
+// TriggerAntiEntropy performs an anti-entropy session on the server.
+func (c *Client) TriggerAntiEntropy() (string, error) {
+	return c.perform(c.admin.TriggerAntiEntropy, &pb.AdminRequest{})
+}
+
+// SnapshotHistory writes the version history of the server to its configured
+// history path. The path must be empty or the configured path.
+func (c *Client) SnapshotHistory(path string) (string, error) {
+	return c.perform(c.admin.SnapshotHistory, &pb.AdminRequest{Path: path})
+}
+
+// DumpMetrics appends the metrics of the server to its configured stats path.
+// The path must be empty or the configured path.
+func (c *Client) DumpMetrics(path string) (string, error) {
+	return c.perform(c.admin.DumpMetrics, &pb.AdminRequest{Path: path})
+}
+
+// perform an admin action, returning the message describing its outcome.
+func (c *Client) perform(action func(context.Context, *pb.AdminRequest, ...grpc.CallOption) (*pb.AdminReply, error), req *pb.AdminRequest) (string, error) {
+	if !c.IsConnected() {
+		return "", errors.New("not connected, cannot make a request")
+	}
+
+	reply, err := action(context.Background(), req)
+	if err != nil {
+		return "", err
+	}
+
+	if !reply.Success {
+		return "", errors.New(reply.Error)
+	}
+
+	return reply.Message, nil
+}
@@ -3,12 +3,16 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/bbengfort/honu"
 	"github.com/joho/godotenv"
 	"github.com/urfave/cli"
+	"golang.org/x/net/context"
 )
 
 func main() {
@@ -21,6 +25,26 @@ func main() {
 	app.Name = "honu"
 	app.Version = honu.PackageVersion
 	app.Usage = "throughput testing for a volatile, in-memory key/value store"
+	app.Before = initLogging
+
+	// Define global flags that configure logging for every command
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "log-level",
+			Usage:  "log level and per-component levels, e.g. info,gossip=debug (components: " + strings.Join(honu.Components, ", ") + ")",
+			EnvVar: "HONU_LOG_LEVEL",
+		},
+		cli.BoolFlag{
+			Name:   "log-json",
+			Usage:  "write log messages as JSON objects, one per line",
+			EnvVar: "HONU_LOG_JSON",
+		},
+		cli.StringFlag{
+			Name:   "log-file",
+			Usage:  "path on disk to append log messages to instead of stdout",
+			EnvVar: "HONU_LOG_FILE",
+		},
+	}
 
 	// Define commands available to the application
 	app.Commands = []cli.Command{
@@ -43,21 +67,138 @@ func main() {
 				},
 				cli.Uint64Flag{
 					Name:   "i, pid",
-					Usage:  "unique process id of server",
-					Value:  1,
+					Usage:  "unique process id of server, required to replicate with peers",
 					EnvVar: "HONU_PROCESS_ID",
 				},
+				cli.StringFlag{
+					Name:   "C, cluster",
+					Usage:  "identifier of the cluster, replicas in other clusters are refused",
+					EnvVar: "HONU_CLUSTER_ID",
+				},
+				cli.StringFlag{
+					Name:   "region",
+					Usage:  "label of the location of the replica for contextual peer selection",
+					Value:  "",
+					EnvVar: "HONU_REGION",
+				},
 				cli.StringFlag{
 					Name:   "p, peers",
 					Usage:  "comma delmited list of address of remote replicas",
 					EnvVar: "HONU_PEERS",
 				},
+				cli.StringFlag{
+					Name:   "j, join",
+					Usage:  "comma delimited list of seed peers to join the cluster through",
+					EnvVar: "HONU_SEEDS",
+				},
+				cli.StringFlag{
+					Name:   "bootstrap-from",
+					Usage:  "address of a peer to load a snapshot of the namespace from on start",
+					EnvVar: "HONU_BOOTSTRAP_FROM",
+				},
 				cli.StringFlag{
 					Name:   "d, delay",
 					Usage:  "parsable duration of anti-entropy delay",
 					Value:  "1s",
 					EnvVar: "HONU_ANTI_ENTROPY_DELAY",
 				},
+				cli.BoolFlag{
+					Name:   "adaptive",
+					Usage:  "adapt the anti-entropy delay to the rate of synchronization",
+					EnvVar: "HONU_ADAPTIVE_DELAY",
+				},
+				cli.StringFlag{
+					Name:   "min-delay",
+					Usage:  "parsable duration of the shortest adaptive anti-entropy delay",
+					Value:  "100ms",
+					EnvVar: "HONU_MIN_DELAY",
+				},
+				cli.StringFlag{
+					Name:   "max-delay",
+					Usage:  "parsable duration of the longest adaptive anti-entropy delay",
+					Value:  "10s",
+					EnvVar: "HONU_MAX_DELAY",
+				},
+				cli.Float64Flag{
+					Name:   "jitter",
+					Usage:  "fraction in [0, 1) to randomize the adaptive anti-entropy delay by",
+					Value:  0.1,
+					EnvVar: "HONU_DELAY_JITTER",
+				},
+				cli.Float64Flag{
+					Name:   "busy-writes",
+					Usage:  "local writes per second above which the adaptive delay is shortened",
+					Value:  10.0,
+					EnvVar: "HONU_BUSY_WRITES",
+				},
+				cli.StringFlag{
+					Name:   "compression",
+					Usage:  "compress gossiped values with the codec (none, gzip, snappy)",
+					Value:  "none",
+					EnvVar: "HONU_COMPRESSION",
+				},
+				cli.Uint64Flag{
+					Name:   "message-limit",
+					Usage:  "maximum bytes of entries per gossip message, 0 for unlimited",
+					Value:  honu.DefaultMessageLimit,
+					EnvVar: "HONU_MESSAGE_LIMIT",
+				},
+				cli.Float64Flag{
+					Name:   "bandwidth",
+					Usage:  "bytes per second budget of gossip with each peer, 0 for unlimited",
+					Value:  0.0,
+					EnvVar: "HONU_BANDWIDTH",
+				},
+				cli.BoolFlag{
+					Name:   "eager",
+					Usage:  "push every write to the peers, hinting writes for unreachable peers",
+					EnvVar: "HONU_EAGER_PUSH",
+				},
+				cli.IntFlag{
+					Name:   "max-hints",
+					Usage:  "maximum number of hinted writes stored for each unreachable peer",
+					Value:  10000,
+					EnvVar: "HONU_MAX_HINTS",
+				},
+				cli.IntFlag{
+					Name:   "read-repair",
+					Usage:  "number of peers to repair the version of each key read with, 0 to disable",
+					Value:  0,
+					EnvVar: "HONU_READ_REPAIR",
+				},
+				cli.BoolFlag{
+					Name:   "streaming",
+					Usage:  "synchronize with peers over streams of batched digests and entries",
+					EnvVar: "HONU_STREAMING",
+				},
+				cli.StringFlag{
+					Name:   "probe",
+					Usage:  "parsable duration of failure detection period, e.g. 1s (disabled by default)",
+					EnvVar: "HONU_PROBE_INTERVAL",
+				},
+				cli.StringFlag{
+					Name:   "probe-timeout",
+					Usage:  "parsable duration to wait for a probe to be acknowledged",
+					Value:  "500ms",
+					EnvVar: "HONU_PROBE_TIMEOUT",
+				},
+				cli.StringFlag{
+					Name:   "suspicion",
+					Usage:  "parsable duration before a suspected peer is declared dead",
+					Value:  "5s",
+					EnvVar: "HONU_SUSPICION_TIMEOUT",
+				},
+				cli.IntFlag{
+					Name:   "indirect",
+					Usage:  "number of peers to probe unresponsive peers through",
+					Value:  3,
+					EnvVar: "HONU_INDIRECT_PROBES",
+				},
+				cli.StringFlag{
+					Name:   "faults",
+					Usage:  "path to a JSON topology of latency, loss and partitions to inject into requests",
+					EnvVar: "HONU_FAULTS_TOPOLOGY",
+				},
 				cli.BoolFlag{
 					Name:   "s, standalone",
 					Usage:  "disable replication and run in standalone mode",
@@ -68,6 +209,17 @@ func main() {
 					Usage:  "pass a parsable duration to shut the server down after",
 					EnvVar: "HONU_SERVER_UPTIME",
 				},
+				cli.StringFlag{
+					Name:   "shutdown-timeout",
+					Usage:  "parsable duration to wait for in-flight RPCs and anti-entropy on shutdown",
+					Value:  honu.DefaultShutdownTimeout.String(),
+					EnvVar: "HONU_SHUTDOWN_TIMEOUT",
+				},
+				cli.StringFlag{
+					Name:   "metrics-addr",
+					Usage:  "address to serve live Prometheus metrics on, e.g. :9090",
+					EnvVar: "HONU_METRICS_ADDR",
+				},
 				cli.StringFlag{
 					Name:   "w, stats",
 					Usage:  "path on disk to write JSON stats to on shutdown",
@@ -79,9 +231,29 @@ func main() {
 					Usage: "path on disk to write version history to on shutdown",
 					Value: "",
 				},
+				cli.StringFlag{
+					Name:   "trace",
+					Usage:  "path on disk to append JSON spans of traced writes and anti-entropy to",
+					EnvVar: "HONU_TRACE_PATH",
+				},
+				cli.Float64Flag{
+					Name:  "trace-sample",
+					Usage: "fraction of writes and anti-entropy sessions to trace",
+					Value: 1.0,
+				},
+				cli.StringFlag{
+					Name:   "report",
+					Usage:  "path on disk to append JSON metrics of every interval to",
+					EnvVar: "HONU_SERVER_REPORT",
+				},
+				cli.StringFlag{
+					Name:  "report-interval",
+					Usage: "parsable duration between interval metrics records",
+					Value: "10s",
+				},
 				cli.StringFlag{
 					Name:   "b, bandit",
-					Usage:  "bandit strategy for random peer selection",
+					Usage:  "bandit strategy for peer selection (uniform, epsilon, annealing, ucb1, discounted-ucb, sliding-window-ucb, bayes-ucb, thompson-beta, thompson-gaussian, softmax, exp3, linucb)",
 					Value:  "uniform",
 					EnvVar: "HONU_BANDIT_STRATEGY",
 				},
@@ -91,6 +263,108 @@ func main() {
 					Value:  0.2,
 					EnvVar: "HONU_BANDIT_EPSILON",
 				},
+				cli.Float64Flag{
+					Name:   "temperature",
+					Usage:  "temperature of the softmax bandit strategy",
+					Value:  0.1,
+					EnvVar: "HONU_BANDIT_TEMPERATURE",
+				},
+				cli.Float64Flag{
+					Name:   "gamma",
+					Usage:  "exploration rate of the exp3 bandit strategy",
+					Value:  0.1,
+					EnvVar: "HONU_BANDIT_GAMMA",
+				},
+				cli.Float64Flag{
+					Name:   "confidence",
+					Usage:  "standard deviations of the bayes-ucb upper bound",
+					Value:  2.0,
+					EnvVar: "HONU_BANDIT_CONFIDENCE",
+				},
+				cli.Float64Flag{
+					Name:   "decay",
+					Usage:  "constant step size of bandit reward estimates (0 for the mean)",
+					Value:  0.0,
+					EnvVar: "HONU_BANDIT_DECAY",
+				},
+				cli.Float64Flag{
+					Name:   "discount",
+					Usage:  "discount factor of past bandit rewards (0 for no discount)",
+					Value:  0.0,
+					EnvVar: "HONU_BANDIT_DISCOUNT",
+				},
+				cli.IntFlag{
+					Name:   "window",
+					Usage:  "number of recent bandit rewards to estimate from (0 for all)",
+					Value:  0,
+					EnvVar: "HONU_BANDIT_WINDOW",
+				},
+				cli.IntFlag{
+					Name:   "bandit-history",
+					Usage:  "number of recent bandit rewards to keep in memory for the metrics",
+					Value:  honu.BanditHistorySize,
+					EnvVar: "HONU_BANDIT_HISTORY",
+				},
+				cli.StringFlag{
+					Name:   "bandit-log",
+					Usage:  "stream every bandit selection and reward to the specified path",
+					Value:  "",
+					EnvVar: "HONU_BANDIT_LOG",
+				},
+				cli.StringFlag{
+					Name:   "reward",
+					Usage:  "anti-entropy reward function (default, latency, versions-per-byte, staleness, weighted)",
+					Value:  "default",
+					EnvVar: "HONU_REWARD",
+				},
+				cli.StringFlag{
+					Name:   "reward-latency",
+					Usage:  "latency at which the latency reward is one half",
+					Value:  "50ms",
+					EnvVar: "HONU_REWARD_LATENCY",
+				},
+				cli.Float64Flag{
+					Name:   "reward-density",
+					Usage:  "versions per KB at which the versions-per-byte reward is one half",
+					Value:  1.0,
+					EnvVar: "HONU_REWARD_DENSITY",
+				},
+				cli.Float64Flag{
+					Name:   "reward-staleness",
+					Usage:  "versions of staleness reduced at which the staleness reward is one half",
+					Value:  10.0,
+					EnvVar: "HONU_REWARD_STALENESS",
+				},
+				cli.StringFlag{
+					Name:   "reward-weights",
+					Usage:  "weights of the weighted reward, e.g. latency=0.5,staleness=0.5",
+					Value:  "",
+					EnvVar: "HONU_REWARD_WEIGHTS",
+				},
+				cli.StringFlag{
+					Name:   "reward-config",
+					Usage:  "JSON file of the weights of the weighted reward",
+					Value:  "",
+					EnvVar: "HONU_REWARD_CONFIG",
+				},
+				cli.StringFlag{
+					Name:   "checkpoint",
+					Usage:  "path to save and warm-start the learned bandit state from",
+					Value:  "",
+					EnvVar: "HONU_BANDIT_CHECKPOINT",
+				},
+				cli.StringFlag{
+					Name:   "checkpoint-interval",
+					Usage:  "parsable duration between bandit checkpoints, empty to only save on shutdown",
+					Value:  "30s",
+					EnvVar: "HONU_BANDIT_CHECKPOINT_INTERVAL",
+				},
+				cli.Float64Flag{
+					Name:   "checkpoint-decay",
+					Usage:  "factor in [0, 1] to reduce the confidence in the loaded bandit checkpoint by",
+					Value:  1.0,
+					EnvVar: "HONU_BANDIT_CHECKPOINT_DECAY",
+				},
 				cli.StringFlag{
 					Name:   "V, visibility",
 					Usage:  "log version visibility at the specified path",
@@ -147,6 +421,37 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:     "status",
+			Usage:    "print the state of a running server and perform admin actions",
+			Action:   status,
+			Category: "client",
+			Before:   initClient,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "a, addr",
+					Usage:  "ip address of the remote server",
+					Value:  "localhost" + honu.DefaultAddr,
+					EnvVar: "HONU_SERVER_ADDR",
+				},
+				cli.StringFlag{
+					Name:  "l, log-level",
+					Usage: "set the log level of the server and its components, e.g. info,gossip=debug",
+				},
+				cli.BoolFlag{
+					Name:  "s, sync",
+					Usage: "trigger an anti-entropy session on the server",
+				},
+				cli.BoolFlag{
+					Name:  "o, snapshot",
+					Usage: "write the version history of the server to its history path",
+				},
+				cli.BoolFlag{
+					Name:  "w, dump",
+					Usage: "append the metrics of the server to its stats path",
+				},
+			},
+		},
 		{
 			Name:     "bench",
 			Usage:    "run the throughput experiment",
@@ -194,6 +499,16 @@ func main() {
 					Name:  "p, prefix",
 					Usage: "key for clients to access, char for prefix, blank for random",
 				},
+				cli.StringFlag{
+					Name:   "report",
+					Usage:  "path on disk to append JSON throughput and latency of every interval to",
+					EnvVar: "HONU_CLIENT_REPORT",
+				},
+				cli.StringFlag{
+					Name:  "report-interval",
+					Usage: "parsable duration between interval throughput records",
+					Value: "1s",
+				},
 			},
 		},
 	}
@@ -202,24 +517,97 @@ func main() {
 	app.Run(os.Args)
 }
 
+//===========================================================================
+// Logging
+//===========================================================================
+
+// Configure the log level, format and destination from the global flags
+func initLogging(c *cli.Context) error {
+	if level := c.GlobalString("log-level"); level != "" {
+		if err := honu.SetLogLevels(level); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if c.GlobalBool("log-json") {
+		honu.LogJSON(true)
+	}
+
+	if path := c.GlobalString("log-file"); path != "" {
+		if err := honu.LogFile(path); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	return nil
+}
+
 //===========================================================================
 // Server Commands
 //===========================================================================
 
 // Run the storage server
 func serve(c *cli.Context) error {
-	// Create the server
-	server := honu.NewServer(c.Uint64("pid"), c.Bool("relax"))
-
-	// Parse the peers variable
-	var peers []string
+	// Parse the peers and seeds variables
+	var peers, seeds []string
 	if c.String("peers") != "" {
 		peers = strings.Split(c.String("peers"), ",")
 	}
 
+	if c.String("join") != "" {
+		seeds = strings.Split(c.String("join"), ",")
+	}
+
+	// Replicas identify each other by pid, so two replicas with the default
+	// pid would each drop the other as a connection to itself.
+	pid := c.Uint64("pid")
+	if pid == 0 {
+		if !c.Bool("standalone") && (len(peers) > 0 || len(seeds) > 0) {
+			return cli.NewExitError("specify a unique --pid for each replica to replicate with peers", 1)
+		}
+		pid = 1
+	}
+
+	// Create the server
+	server := honu.NewServer(pid, c.Bool("relax"))
+
+	// Set the cluster the server belongs to
+	server.Cluster(c.String("cluster"))
+
+	// Serve live metrics if specified
+	if c.String("metrics-addr") != "" {
+		if err := server.ServeMetrics(c.String("metrics-addr")); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	// Load the namespace from a peer before serving if specified
+	if c.String("bootstrap-from") != "" {
+		server.BootstrapFrom(c.String("bootstrap-from"))
+	}
+
 	// Set the stats and version dump paths
 	server.Measure(c.String("stats"), c.String("history"))
 
+	// Report the metrics of every interval if specified
+	if path := c.String("report"); path != "" {
+		interval, err := time.ParseDuration(c.String("report-interval"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if err := server.ReportMetrics(path, interval); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	// Trace writes as they propagate if specified
+	if path := c.String("trace"); path != "" {
+		if err := server.Trace(path, c.Float64("trace-sample")); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
 	// Set the visibility log
 	if c.String("visibility") != "" {
 		if err := server.Visibility(c.String("visibility")); err != nil {
@@ -227,8 +615,9 @@ func serve(c *cli.Context) error {
 		}
 	}
 
-	// Run replication service
-	if !c.Bool("standalone") && len(peers) > 0 {
+	// Run replication service; replicas without peers or seeds can still be
+	// joined by other replicas using them as a seed.
+	if !c.Bool("standalone") {
 		// Parse the delay variable
 		delay, err := time.ParseDuration(c.String("delay"))
 		if err != nil {
@@ -236,13 +625,142 @@ func serve(c *cli.Context) error {
 		}
 
 		bandit := c.String("bandit")
-		epsilon := c.Float64("epsilon")
+		opts := honu.BanditOptions{
+			Epsilon:     c.Float64("epsilon"),
+			Temperature: c.Float64("temperature"),
+			Gamma:       c.Float64("gamma"),
+			Confidence:  c.Float64("confidence"),
+			Decay:       c.Float64("decay"),
+			Discount:    c.Float64("discount"),
+			Window:      c.Int("window"),
+			History:     c.Int("bandit-history"),
+		}
 
-		if err := server.Replicate(peers, delay, bandit, epsilon); err != nil {
+		if err := server.Replicate(peers, delay, bandit, opts); err != nil {
 			return cli.NewExitError(err.Error(), 1)
 		}
+
+		// Configure the reward function of the bandit
+		ropts := honu.RewardOptions{
+			Density:   c.Float64("reward-density"),
+			Staleness: c.Float64("reward-staleness"),
+		}
+
+		if ropts.Latency, err = time.ParseDuration(c.String("reward-latency")); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if path := c.String("reward-config"); path != "" {
+			if ropts.Weights, err = honu.LoadRewardWeights(path); err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		} else if ropts.Weights, err = honu.ParseRewardWeights(c.String("reward-weights")); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		reward, err := honu.NewRewardFunction(c.String("reward"), ropts)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		server.Reward(reward)
+
+		// Stream the bandit history if specified
+		if path := c.String("bandit-log"); path != "" {
+			if err := server.BanditLog(path); err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		}
+
+		// Warm-start and checkpoint the bandit if specified
+		if path := c.String("checkpoint"); path != "" {
+			var interval time.Duration
+			if c.String("checkpoint-interval") != "" {
+				if interval, err = time.ParseDuration(c.String("checkpoint-interval")); err != nil {
+					return cli.NewExitError(err.Error(), 1)
+				}
+			}
+
+			if err := server.Checkpoint(path, interval, c.Float64("checkpoint-decay")); err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		}
+
+		server.Seeds(seeds)
+		server.Region(c.String("region"))
+
+		// Configure the bandwidth used by anti-entropy
+		if err := server.Compression(c.String("compression")); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		server.MessageLimit(c.Uint64("message-limit"))
+		server.BandwidthBudget(c.Float64("bandwidth"))
+		server.Streaming(c.Bool("streaming"))
+
+		// Push writes eagerly with hinted handoff if specified
+		if c.Bool("eager") {
+			server.EagerPush(c.Int("max-hints"))
+		}
+
+		// Repair reads in the background if specified
+		if c.Int("read-repair") > 0 {
+			server.RepairReads(c.Int("read-repair"))
+		}
+
+		// Configure the adaptive anti-entropy delay
+		if c.Bool("adaptive") {
+			durations := make([]time.Duration, 2)
+			for i, flag := range []string{"min-delay", "max-delay"} {
+				if durations[i], err = time.ParseDuration(c.String(flag)); err != nil {
+					return cli.NewExitError(err.Error(), 1)
+				}
+			}
+
+			if durations[0] <= 0 || durations[0] > durations[1] {
+				return cli.NewExitError("minimum delay must be positive and less than the maximum delay", 1)
+			}
+
+			if jitter := c.Float64("jitter"); jitter < 0 || jitter >= 1 {
+				return cli.NewExitError("jitter must be a fraction in [0, 1)", 1)
+			}
+
+			if err := server.AdaptiveDelay(durations[0], durations[1], c.Float64("jitter"), c.Float64("busy-writes")); err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		}
+
+		// Configure the failure detector
+		if c.String("probe") != "" {
+			durations := make([]time.Duration, 3)
+			for i, flag := range []string{"probe", "probe-timeout", "suspicion"} {
+				if durations[i], err = time.ParseDuration(c.String(flag)); err != nil {
+					return cli.NewExitError(err.Error(), 1)
+				}
+			}
+
+			server.DetectFailures(durations[0], durations[1], durations[2], c.Int("indirect"))
+		}
+
+		// Emulate the network between peers if specified
+		if path := c.String("faults"); path != "" {
+			topology, err := honu.LoadTopology(path)
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+
+			if err := server.InjectFaults(topology); err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		}
 	}
 
+	// Set the time to wait for in-flight work on shutdown
+	grace, err := time.ParseDuration(c.String("shutdown-timeout"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	server.ShutdownTimeout(grace)
+
 	// Set the uptime timer
 	if c.String("uptime") != "" {
 		// Parse the delay variable
@@ -254,14 +772,29 @@ func serve(c *cli.Context) error {
 		server.Uptime(uptime)
 	}
 
-	// Run the server (blocks)
-	if err := server.Run(c.String("addr")); err != nil {
-		return cli.NewExitError(err.Error(), 1)
+	// Run the server until interrupted (blocks)
+	if err := server.Run(signalContext(), c.String("addr")); err != nil {
+		return cli.NewExitError(fmt.Sprintf("could not gracefully shutdown: %s", err), 1)
 	}
 
 	return nil
 }
 
+// Returns a context that is cancelled when the process is interrupted or
+// terminated, so that the server can shutdown gracefully.
+func signalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigchan := make(chan os.Signal, 1)
+	signal.Notify(sigchan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigchan
+		cancel()
+	}()
+
+	return ctx
+}
+
 //===========================================================================
 // Client Commands
 //===========================================================================
@@ -300,6 +833,74 @@ func put(c *cli.Context) error {
 	return nil
 }
 
+// Perform admin actions on the server and print its status
+func status(c *cli.Context) error {
+	if level := c.String("log-level"); level != "" {
+		msg, err := client.SetLogLevel(level)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		fmt.Println(msg)
+	}
+
+	if c.Bool("sync") {
+		msg, err := client.TriggerAntiEntropy()
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		fmt.Println(msg)
+	}
+
+	if c.Bool("snapshot") {
+		msg, err := client.SnapshotHistory("")
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		fmt.Println(msg)
+	}
+
+	if c.Bool("dump") {
+		msg, err := client.DumpMetrics("")
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		fmt.Println(msg)
+	}
+
+	reply, err := client.Status()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	uptime := time.Duration(reply.Uptime * float64(time.Second))
+	fmt.Printf("replica %d at %s in cluster %q\n", reply.Pid, reply.Addr, reply.Cluster)
+	fmt.Printf("  uptime:    %s\n", uptime)
+	fmt.Printf("  store:     %s with %d keys and %d versions\n", reply.Store, reply.Keys, reply.Versions)
+	fmt.Printf("  accesses:  %d reads, %d writes\n", reply.Reads, reply.Writes)
+	fmt.Printf("  log level: %s\n", reply.LogLevel)
+
+	if reply.Bandit == "" {
+		fmt.Println("  not replicating")
+		return nil
+	}
+
+	fmt.Printf("  bandit:    %s with %d peers\n\n", reply.Bandit, len(reply.Peers))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PEER\tSTATUS\tCOUNT\tVALUE\tSYNCS\tPULLS\tPUSHES\tMISSES\tVERSIONS\tSENT\tRECEIVED\tPULL\tPUSH\tLAST SYNC")
+	for _, peer := range reply.Peers {
+		fmt.Fprintf(
+			w, "%s\t%s\t%d\t%0.3f\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\t%s\n",
+			peer.Addr, peer.Status, peer.Count, peer.Value, peer.Syncs, peer.Pulls,
+			peer.Pushes, peer.Misses, peer.Versions, peer.Sent, peer.Received,
+			time.Duration(peer.PullLatency*float64(time.Second)),
+			time.Duration(peer.PushLatency*float64(time.Second)),
+			peer.LastSync,
+		)
+	}
+	return w.Flush()
+}
+
 // Run the throughput experiment
 func bench(c *cli.Context) error {
 	duration, err := time.ParseDuration(c.String("duration"))
@@ -329,6 +930,18 @@ func bench(c *cli.Context) error {
 		return cli.NewExitError(err.Error(), 1)
 	}
 
+	// Report the throughput and latency of every interval if specified
+	if path := c.String("report"); path != "" {
+		interval, err := time.ParseDuration(c.String("report-interval"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if err := bench.Report(path, interval); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
 	if err := bench.Run(c.String("addr"), c.String("results"), duration, delay, rate); err != nil {
 		return cli.NewExitError(err.Error(), 1)
 	}
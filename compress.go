@@ -0,0 +1,240 @@
+package honu
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/bbengfort/honu/rpc"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+)
+
+// DefaultMessageLimit is the default maximum size in bytes of the entries in
+// a single gossip message, well below the gRPC message size limit.
+const DefaultMessageLimit = 1024 * 1024
+
+//===========================================================================
+// Value Compression
+//===========================================================================
+
+// Codec compresses the values of entries that are sent by gossip.
+type Codec interface {
+	Name() string                        // The name of the encoding sent with entries
+	Encode(value []byte) ([]byte, error) // Compress the value
+	Decode(value []byte) ([]byte, error) // Decompress the value
+}
+
+// NewCodec returns the codec with the specified name, or nil if the name is
+// empty or none, meaning values are not compressed.
+func NewCodec(name string) (Codec, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return nil, nil
+	case "gzip":
+		return gzipCodec{}, nil
+	case "snappy":
+		return snappyCodec{}, nil
+	default:
+		return nil, fmt.Errorf("no compression codec named %s", name)
+	}
+}
+
+// gzipCodec compresses values with gzip.
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) Encode(value []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(value); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(value []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// snappyCodec compresses values with snappy, which is faster but compresses
+// less than gzip.
+type snappyCodec struct{}
+
+func (snappyCodec) Name() string {
+	return "snappy"
+}
+
+func (snappyCodec) Encode(value []byte) ([]byte, error) {
+	return snappy.Encode(nil, value), nil
+}
+
+func (snappyCodec) Decode(value []byte) ([]byte, error) {
+	return snappy.Decode(nil, value)
+}
+
+// encodeEntry converts the entry to a protocol buffer, compressing the value
+// with the codec if it is not nil. Values that do not get smaller are sent
+// uncompressed.
+func encodeEntry(entry *Entry, codec Codec) *pb.Entry {
+	out := entry.topb()
+	if codec == nil || len(entry.Value) == 0 {
+		return out
+	}
+
+	value, err := codec.Encode(entry.Value)
+	if err != nil {
+		warne(err)
+		return out
+	}
+
+	if len(value) < len(entry.Value) {
+		out.Value = value
+		out.Encoding = codec.Name()
+	}
+	return out
+}
+
+// decodeEntry converts the protocol buffer to an entry, decompressing the
+// value with the codec named by its encoding.
+func decodeEntry(in *pb.Entry) (*Entry, error) {
+	entry := new(Entry)
+	entry.frompb(in)
+
+	if in.Encoding == "" {
+		return entry, nil
+	}
+
+	codec, err := NewCodec(in.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Value, err = codec.Decode(in.Value); err != nil {
+		return nil, fmt.Errorf("could not decode %s value: %s", in.Encoding, err)
+	}
+	return entry, nil
+}
+
+// Compression sets the codec that values are compressed with when they are
+// pushed to peers or pulled from them. Values are only pushed compressed to
+// peers that have acknowledged the codec, so that peers running builds that
+// do not decode values are sent them uncompressed.
+func (s *Server) Compression(name string) (err error) {
+	s.codec, err = NewCodec(name)
+	return err
+}
+
+// acknowledged records the encoding that the peer requested pushed values to
+// be compressed with when it replied to a pull or sync. Peers that do not
+// request an encoding are pushed uncompressed values.
+func (s *Server) acknowledged(peer, encoding string) {
+	codec, err := NewCodec(encoding)
+	if err != nil {
+		debug("pushing uncompressed values to %s: %s", peer, err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if codec == nil {
+		delete(s.encodings, peer)
+		return
+	}
+	s.encodings[peer] = codec
+}
+
+// pushCodec returns the codec that values pushed to the peer are compressed
+// with, or nil if the peer has not acknowledged one.
+func (s *Server) pushCodec(peer string) Codec {
+	s.Lock()
+	defer s.Unlock()
+	return s.encodings[peer]
+}
+
+// MessageLimit sets the maximum size in bytes of the entries in a single push
+// or pull reply; larger synchronizations are split into multiple messages.
+// Each message contains at least one entry. If zero, messages are unlimited.
+func (s *Server) MessageLimit(limit uint64) {
+	s.limit = limit
+}
+
+// BandwidthBudget limits the bytes sent to and received from each peer per
+// second, allowing bursts of up to one second of bandwidth. If the budget of
+// a peer is exhausted, synchronization with it is deferred until the budget
+// is replenished. If zero, bandwidth is unlimited.
+func (s *Server) BandwidthBudget(rate float64) {
+	s.budget = rate
+}
+
+// entrySize returns the approximate size of the entry in a map of entries.
+func entrySize(key string, entry *pb.Entry) uint64 {
+	n := proto.Size(entry)
+	return uint64(len(key) + n + proto.SizeVarint(uint64(n)) + 8)
+}
+
+//===========================================================================
+// Bandwidth Budget
+//===========================================================================
+
+// bandwidth is a token bucket of bytes that can be exchanged with a peer.
+// The bucket may go into debt so that messages larger than the burst can be
+// sent, but no messages are sent until the debt has been repaid.
+type bandwidth struct {
+	sync.Mutex
+	rate   float64   // Bytes replenished per second
+	tokens float64   // Bytes that can be exchanged
+	last   time.Time // The last time the tokens were replenished
+}
+
+// newBandwidth creates a full bucket with the rate in bytes per second.
+func newBandwidth(rate float64) *bandwidth {
+	return &bandwidth{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// Available returns true if there is budget to exchange messages.
+func (b *bandwidth) Available() bool {
+	if b == nil {
+		return true
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	b.replenish()
+	return b.tokens > 0
+}
+
+// Consume the number of bytes that were exchanged from the budget.
+func (b *bandwidth) Consume(n int) {
+	if b == nil {
+		return
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	b.replenish()
+	b.tokens -= float64(n)
+}
+
+// replenish the tokens since the last time they were replenished.
+func (b *bandwidth) replenish() {
+	now := time.Now()
+	b.tokens = math.Min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
@@ -3,10 +3,16 @@
 package honu
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Levels for implementing the debug and trace message functionality.
@@ -23,14 +29,78 @@ const (
 // CautionThreshold for issuing caution logs after accumulating cautions.
 const CautionThreshold = 80
 
+// Components of the system whose log levels can be set independently of the
+// global log level.
+var Components = []string{"store", "gossip", "bandit", "rpc", "client"}
+
+// The component that logs the messages of each source file; messages from
+// files that are not listed are logged at the global level.
+var componentFiles = map[string]string{
+	"store.go":      "store",
+	"history.go":    "store",
+	"entry.go":      "store",
+	"entropy.go":    "gossip",
+	"stream.go":     "gossip",
+	"bootstrap.go":  "gossip",
+	"hints.go":      "gossip",
+	"repair.go":     "gossip",
+	"members.go":    "gossip",
+	"swim.go":       "gossip",
+	"handshake.go":  "gossip",
+	"compress.go":   "gossip",
+	"faults.go":     "gossip",
+	"bandit.go":     "bandit",
+	"banditlog.go":  "bandit",
+	"reward.go":     "bandit",
+	"features.go":   "bandit",
+	"checkpoint.go": "bandit",
+	"schedule.go":   "bandit",
+	"server.go":     "rpc",
+	"admin.go":      "rpc",
+	"prometheus.go": "rpc",
+	"tracing.go":    "rpc",
+	"client.go":     "client",
+	"bench.go":      "client",
+}
+
 // These variables are initialized in init()
 var logLevel = Caution
 var logger *log.Logger
+var logging *logConfig
 var cautionCounter *counter
 var logLevelStrings = [...]string{
 	"trace", "debug", "info", "caution", "status", "warn", "silent",
 }
 
+// logConfig is the destination and format of log messages and the levels of
+// each component.
+type logConfig struct {
+	sync.RWMutex
+	json       bool             // Write messages as JSON objects rather than text
+	out        io.Writer        // The destination of JSON messages
+	file       *os.File         // The log file, if messages are not logged to stdout
+	components map[string]uint8 // The levels of components that differ from the global level
+	minimum    uint8            // The lowest level of the global level and any component
+}
+
+func (c *logConfig) init() {
+	c.out = os.Stdout
+	c.components = make(map[string]uint8)
+	c.minimum = logLevel
+}
+
+// updateMinimum computes the lowest level that any message is logged at.
+// Must be called under lock.
+func (c *logConfig) updateMinimum() {
+	c.minimum = logLevel
+	for _, level := range c.components {
+		if level < c.minimum {
+			c.minimum = level
+		}
+	}
+}
+
+// counter counts the cautions logged by their message template.
 type counter struct {
 	sync.Mutex
 	counts map[string]uint
@@ -49,6 +119,17 @@ func LogLevel() string {
 	return logLevelStrings[logLevel]
 }
 
+// ParseLogLevel returns the level with the specified name.
+func ParseLogLevel(name string) (uint8, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for level, str := range logLevelStrings {
+		if name == str {
+			return uint8(level), nil
+		}
+	}
+	return 0, fmt.Errorf("no log level named %q", name)
+}
+
 // SetLogLevel modifies the log level for messages at runtime. Ensures that
 // the highest level that can be set is the trace level.
 func SetLogLevel(level uint8) {
@@ -56,23 +137,212 @@ func SetLogLevel(level uint8) {
 		level = Silent
 	}
 
+	logging.Lock()
+	defer logging.Unlock()
+
 	logLevel = level
+	logging.updateMinimum()
+}
+
+// SetComponentLevel modifies the log level of the messages of a component at
+// runtime, independently of the global log level.
+func SetComponentLevel(component string, level uint8) error {
+	if !isComponent(component) {
+		return fmt.Errorf("no component named %q, specify one of %s", component, strings.Join(Components, ", "))
+	}
+
+	if level > Silent {
+		level = Silent
+	}
+
+	logging.Lock()
+	defer logging.Unlock()
+
+	logging.components[component] = level
+	logging.updateMinimum()
+	return nil
+}
+
+// SetLogLevels parses a comma separated list of the global level and the
+// levels of components, e.g. "info,gossip=debug,store=warn", and sets them.
+// Levels that are not specified are not modified.
+func SetLogLevels(spec string) error {
+	for _, part := range strings.Split(spec, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		var component string
+		if idx := strings.Index(part, "="); idx >= 0 {
+			component = strings.TrimSpace(part[:idx])
+			part = part[idx+1:]
+		}
+
+		level, err := ParseLogLevel(part)
+		if err != nil {
+			return err
+		}
+
+		if component == "" {
+			SetLogLevel(level)
+			continue
+		}
+
+		if err := SetComponentLevel(component, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogLevels returns the global level and the levels of components that were
+// set, in the format accepted by SetLogLevels.
+func LogLevels() string {
+	logging.RLock()
+	defer logging.RUnlock()
+
+	levels := []string{LogLevel()}
+	for _, component := range Components {
+		if level, ok := logging.components[component]; ok {
+			levels = append(levels, fmt.Sprintf("%s=%s", component, logLevelStrings[level]))
+		}
+	}
+	return strings.Join(levels, ",")
+}
+
+// LogJSON sets messages to be logged as JSON objects with the time, level,
+// component and message, along with the key/value pairs of the message.
+func LogJSON(enabled bool) {
+	logging.Lock()
+	defer logging.Unlock()
+	logging.json = enabled
+}
+
+// LogFile appends messages to the file at the path rather than stdout.
+func LogFile(path string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	logging.Lock()
+	defer logging.Unlock()
+
+	if logging.file != nil {
+		logging.file.Close()
+	}
+
+	logging.file = f
+	logging.out = f
+	logger.SetOutput(f)
+	return nil
+}
+
+// isComponent returns true if the component's level can be set.
+func isComponent(component string) bool {
+	for _, c := range Components {
+		if c == component {
+			return true
+		}
+	}
+	return false
 }
 
 //===========================================================================
 // Debugging output functions
 //===========================================================================
 
-// Print to the standard logger at the specified level. Arguments are handled
-// in the manner of log.Printf, but a newline is appended.
+// Print to the standard logger at the specified level if it is enabled for
+// the component of the caller. Arguments are handled in the manner of
+// log.Printf, but a newline is appended. Must be called directly by the
+// logging functions below, since the caller is found by the call depth.
 func print(level uint8, msg string, a ...interface{}) {
-	if level >= logLevel {
-		if !strings.HasSuffix(msg, "\n") {
-			msg += "\n"
+	component, ok := enabled(level)
+	if !ok {
+		return
+	}
+
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+
+	output(level, component, fmt.Sprintf(msg, a...), nil)
+}
+
+// printkv logs a message at the specified level with key/value pairs, which
+// are fields of JSON messages and appended as key=value to text messages.
+// Must be called directly by the logging functions below.
+func printkv(level uint8, msg string, keyvals ...interface{}) {
+	component, ok := enabled(level)
+	if !ok {
+		return
+	}
+
+	output(level, component, msg, keyvals)
+}
+
+// enabled returns the component of the caller of the logging function and
+// whether messages are logged at the level for it. The caller is only
+// looked up if the level is enabled for any component.
+func enabled(level uint8) (string, bool) {
+	logging.RLock()
+	defer logging.RUnlock()
+
+	if level < logging.minimum {
+		return "", false
+	}
+
+	var component string
+	if _, file, _, ok := runtime.Caller(3); ok {
+		component = componentFiles[filepath.Base(file)]
+	}
+
+	if clevel, ok := logging.components[component]; ok {
+		return component, level >= clevel
+	}
+	return component, level >= logLevel
+}
+
+// output writes the message as text with the standard logger, or as a JSON
+// object to the log output.
+func output(level uint8, component, msg string, keyvals []interface{}) {
+	logging.RLock()
+	asJSON := logging.json
+	out := logging.out
+	logging.RUnlock()
+
+	if !asJSON {
+		msg = strings.TrimSuffix(msg, "\n")
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			msg += fmt.Sprintf(" %v=%v", keyvals[i], keyvals[i+1])
 		}
 
-		logger.Printf(msg, a...)
+		logger.Println(msg)
+		return
+	}
+
+	record := make(map[string]interface{}, 4+len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		record[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+
+	record["time"] = time.Now().Format(time.RFC3339Nano)
+	record["level"] = logLevelStrings[level]
+	record["msg"] = strings.TrimSuffix(msg, "\n")
+	if component != "" {
+		record["component"] = component
 	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		data, _ = json.Marshal(map[string]interface{}{
+			"time": record["time"], "level": record["level"], "msg": record["msg"],
+		})
+	}
+
+	logging.Lock()
+	out.Write(append(data, '\n'))
+	logging.Unlock()
 }
 
 // Prints to the standard logger if level is warn or greater; arguments are
@@ -83,7 +353,7 @@ func warn(msg string, a ...interface{}) {
 
 // Helper function to simply warn about an error received.
 func warne(err error) {
-	warn(err.Error())
+	print(Warn, "%s", err)
 }
 
 // Prints to the standard logger if level is status or greater; arguments are
@@ -92,23 +362,23 @@ func status(msg string, a ...interface{}) {
 	print(Status, msg, a...)
 }
 
-// Caution messages only log if the number of the same caution messages is
-// greater than the CautionThreshold, reducing the number of log messages
-// in the system but still reporting valuable information.
-//
-// NOTE: take care with string formatting individual messages, this could
-// lead to a very full caution counter that is taking up memory.
+// Caution messages only log if the number of caution messages with the same
+// template is greater than the CautionThreshold, reducing the number of log
+// messages in the system but still reporting valuable information. The
+// message is logged with the arguments of the caution that reached the
+// threshold.
 func caution(msg string, a ...interface{}) {
 	cautionCounter.Lock()
-	defer cautionCounter.Unlock()
-
-	msg = fmt.Sprintf(msg, a...)
 	cautionCounter.counts[msg]++
-
-	if cautionCounter.counts[msg] >= CautionThreshold {
-		print(Caution, msg)
+	count := cautionCounter.counts[msg]
+	if count >= CautionThreshold {
 		delete(cautionCounter.counts, msg)
 	}
+	cautionCounter.Unlock()
+
+	if count >= CautionThreshold {
+		print(Caution, msg, a...)
+	}
 }
 
 // Prints to the standard logger if level is info or greater; arguments are
@@ -128,3 +398,13 @@ func debug(msg string, a ...interface{}) {
 func trace(msg string, a ...interface{}) {
 	print(Trace, msg, a...)
 }
+
+// Logs a structured message with key/value pairs if level is info or greater.
+func infokv(msg string, keyvals ...interface{}) {
+	printkv(Info, msg, keyvals...)
+}
+
+// Logs a structured message with key/value pairs if level is debug or greater.
+func debugkv(msg string, keyvals ...interface{}) {
+	printkv(Debug, msg, keyvals...)
+}
@@ -4,156 +4,363 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 
 	pb "github.com/bbengfort/honu/rpc"
-	"github.com/bbengfort/x/stats"
 	"golang.org/x/net/context"
 )
 
 // AntiEntropy performs a pairwise, bilateral syncrhonization with a random
 // remote peer, first sending our version vector, then sending any required
-// versions to the remote host.
+// versions to the remote host. The next session is scheduled from the
+// outcome of this one.
+func (s *Server) AntiEntropy() {
+	s.Lock()
+	if s.stopped {
+		s.Unlock()
+		return
+	}
+	s.sessions.Add(1)
+	s.Unlock()
+	defer s.sessions.Done()
+
+	session := new(Synchronization)
+	defer func() {
+		s.scheduleAntiEntropy(s.nextDelay(session))
+	}()
+
+	s.synchronize(session)
+}
+
+// scheduleAntiEntropy schedules the next session after the delay, unless the
+// server has been shut down.
+func (s *Server) scheduleAntiEntropy(delay time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.stopped {
+		s.entropy = time.AfterFunc(delay, s.AntiEntropy)
+	}
+}
+
+// synchronize performs a single anti-entropy session with the peer selected
+// by the bandit, recording its outcome in the session.
 //
 // NOTE: the view specified is the view at the start of anti-entropy.
-func (s *Server) AntiEntropy() {
-	// Schedule the next anti-entropy session
-	defer time.AfterFunc(s.delay, s.AntiEntropy)
+func (s *Server) synchronize(session *Synchronization) {
+	// Select a random peer for pairwise anti-entropy; the peers may change as
+	// replicas join and leave the cluster, so selection is done under lock.
+	s.Lock()
+	arm := -1
+	if len(s.peers) > 0 {
+		if bandit, ok := s.bandit.(ContextualBandit); ok {
+			s.context(bandit)
+		}
+		arm = s.bandit.Select()
+	}
+
+	if arm < 0 {
+		s.Unlock()
+		debug("no live peers to perform anti-entropy with")
+		return
+	}
 
-	// Select a random peer for pairwise anti-entropy
-	reward := 0.0
-	arm := s.bandit.Select()
 	peer := s.peers[arm]
+	metrics := s.syncs[peer]
+	if s.budget > 0 && metrics.bandwidth == nil {
+		metrics.bandwidth = newBandwidth(s.budget)
+	}
+	session.Peer = peer
+	writes := s.writes
+	unary := metrics.unary
+	ctx := s.ctx
+	s.Unlock()
+
+	// Trace the session, the requests to the peer are traced as its children
+	ctx, span := s.tracer.Start(ctx, "anti-entropy")
+	span.SetAttribute("peer", peer)
+	defer func() {
+		span.SetAttribute("pulled", session.PullItems)
+		span.SetAttribute("pushed", session.PushItems)
+		span.Finish()
+	}()
+
+	// Ensure we update the reward for the bandit when we are done. The arm is
+	// looked up again in case the membership changed during synchronization.
+	defer func() {
+		s.Lock()
+		var reward float64
+		arm := s.arm(peer)
+		if arm >= 0 {
+			reward = s.reward.Reward(session)
+			s.bandit.Update(arm, reward)
+		}
 
-	// Ensure we update the reward for the bandit when we are done.
-	defer func() { s.bandit.Update(arm, reward) }()
+		if s.report != nil && session.Latency() > 0 {
+			s.report.syncs.Update(session.Latency())
+		}
+		banditlog := s.banditlog
+		s.Unlock()
 
-	// TODO: do better at ignoring self-connections
-	if peer == s.addr {
-		// Penalize self selection by a lot
-		reward = -1.0
-		s.syncs[peer].Misses++
+		// Log the reward outside of the lock since the log may block when
+		// its buffer is full
+		if arm >= 0 && banditlog != nil {
+			banditlog.Log(arm, reward, session)
+		}
+	}()
+
+	// Defer synchronization if the bandwidth budget of the peer is exhausted
+	if !metrics.bandwidth.Available() {
+		s.updateSync(func() { metrics.Throttled++ })
+		debug("bandwidth budget of %s is exhausted", peer)
 		return
 	}
 
 	// Create a connection to the client
-	conn, err := grpc.Dial(
-		peer, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(timeout),
-	)
+	conn, err := s.dial(ctx, peer)
 
 	if err != nil {
-		s.syncs[peer].Misses++
+		s.updateSync(func() { metrics.Misses++ })
+		span.SetError(err)
 		warn(err.Error())
 		return
 	}
 	defer conn.Close()
 
+	// Establish the identity of the peer, dropping it if it's the local replica
+	if err = s.handshake(conn, peer); err != nil {
+		if err != ErrSelfConnection && err != ErrAliasConnection {
+			s.updateSync(func() { metrics.Misses++ })
+			span.SetError(err)
+			warne(err)
+		}
+		return
+	}
+
+	// Replay the writes the peer missed while it was unreachable
+	if s.handoff != nil {
+		go s.handoff.Replay(peer)
+	}
+
 	// Create a gossip client
 	client := pb.NewGossipClient(conn)
-	debug("connected to anti-entropy peer at %s", peer)
+	debugkv("connected to anti-entropy peer", "peer", peer)
 
 	// Get the current version vector for every object
 	vector := s.store.View()
 
-	// Create the pull request
-	req := &pb.PullRequest{
-		Versions: make(map[string]*pb.Version),
-	}
+	// Synchronize over a stream if enabled, falling back to unary pull and
+	// push for peers that do not implement streaming.
+	if s.streaming && !unary {
+		err := s.syncStream(ctx, client, vector, writes, session, metrics)
+		if err == nil {
+			return
+		}
 
-	for key, version := range vector {
-		req.Versions[key] = version.topb()
+		if grpc.Code(err) != codes.Unimplemented {
+			s.updateSync(func() { metrics.Misses++ })
+			span.SetError(err)
+			warne(err)
+			return
+		}
+
+		s.updateSync(func() { metrics.unary = true })
+		status("%s does not implement streaming, falling back to unary gossip", peer)
 	}
 
-	// Send the pull request
-	pullStart := time.Now()
-	rep, err := client.Pull(context.Background(), req)
-	if err != nil {
-		s.syncs[peer].Misses++
-		warn(err.Error())
-		return
+	// Pull from the remote until the reply is no longer truncated by the
+	// message limit, collecting the versions the remote requests to be pushed.
+	view := vector
+	pull := make(map[string]*pb.Version)
+	for {
+		// Create the pull request, piggybacking our view of the membership
+		req := &pb.PullRequest{
+			Versions: make(map[string]*pb.Version),
+			Members:  s.members.topb(),
+			Limit:    s.limit,
+		}
+
+		if s.codec != nil {
+			req.Encoding = s.codec.Name()
+		}
+
+		for key, version := range view {
+			req.Versions[key] = version.topb()
+		}
+
+		// Send the pull request
+		pullStart := time.Now()
+		rep, err := client.Pull(ctx, req)
+		if err != nil {
+			s.updateSync(func() { metrics.Misses++ })
+			span.SetError(err)
+			warn(err.Error())
+			return
+		}
+
+		pullLatency := time.Since(pullStart)
+		session.PullLatency += pullLatency
+		s.updateSync(func() {
+			metrics.Update(pullLatency, "pull")
+			metrics.LastLatency = pullLatency
+			metrics.exchanged(proto.Size(req), proto.Size(rep))
+		})
+		session.Bytes += uint64(proto.Size(rep))
+
+		// Merge the remote view of the membership
+		s.membershipChanged(s.members.Merge(rep.Members))
+
+		// Handle the pull response
+		if !rep.Success {
+			break
+		}
+
+		session.Pulled = true
+		var items uint64
+
+		for key, pbentry := range rep.Entries {
+			if entry, ok := s.accept(ctx, key, pbentry); ok {
+				items++
+				session.Staleness += staleness(entry.Version, vector[key])
+			}
+		}
+
+		session.PullItems += items
+		if rep.Pull != nil {
+			s.acknowledged(peer, rep.Pull.Encoding)
+			for key, pbvers := range rep.Pull.Versions {
+				pull[key] = pbvers
+			}
+		}
+
+		// Continue pulling the remaining entries if the budget allows
+		if !rep.Truncated || items == 0 {
+			break
+		}
+
+		if !metrics.bandwidth.Available() {
+			s.updateSync(func() { metrics.Throttled++ })
+			debug("bandwidth budget of %s exhausted during pull", peer)
+			break
+		}
+		view = s.store.View()
 	}
-	pullLatency := time.Since(pullStart)
-	s.syncs[peer].Update(pullLatency, "pull")
 
-	// Handle the pull response
-	if !rep.Success {
-		s.syncs[peer].Misses++
+	s.Lock()
+	metrics.LastSync = time.Now()
+	metrics.writes = writes
+
+	if !session.Pulled {
+		metrics.Misses++
+		s.Unlock()
 		debug("no synchronization occurred")
 		return
 	}
 
-	reward += 0.25 // add reward for a successful pull request
+	metrics.Pulls++
+	s.Unlock()
 
-	// add reward for low latency pull requests
-	if pullLatency < 5*time.Millisecond {
-		reward += 0.20 // highest reward for local latencies
-	} else if pullLatency <= 100*time.Millisecond {
-		reward += 0.10 // reward for close by links that don't globe span.
-	}
+	// Send the push request (bilateral), split into messages that are within
+	// the message limit. Can be fire and forget if needed
+	if len(pull) > 0 {
+		debug("pushing %d versions back to %s", len(pull), peer)
 
-	s.syncs[peer].Pulls++
-	var items uint64
+		var size, stale uint64
+		codec := s.pushCodec(peer)
+		push := &pb.PushRequest{Entries: make(map[string]*pb.Entry)}
 
-	for key, pbentry := range rep.Entries {
-		entry := new(Entry)
-		entry.frompb(pbentry)
-		if s.store.PutEntry(key, entry) {
-			items++
+		for key, pbvers := range pull {
+			s.store.RLock()
+			entry := s.store.GetEntry(key).copy()
+			s.store.RUnlock()
 
-			// Track visibility if requested
-			if s.visibility != nil && entry.TrackVisibility {
-				s.visibility.Log(key, entry.Version.String())
-				if err := s.visibility.Error(); err != nil {
-					warne(err)
+			pbentry := encodeEntry(entry, codec)
+			n := entrySize(key, pbentry)
+
+			if s.limit > 0 && len(push.Entries) > 0 && size+n > s.limit {
+				if !s.push(ctx, client, push, stale, session, metrics) {
+					break
 				}
-			}
-		}
-	}
 
-	if items > 1 {
-		// add reward for multi-items
-		reward += 0.05
-	}
+				push = &pb.PushRequest{Entries: make(map[string]*pb.Entry)}
+				size, stale = 0, 0
+			}
 
-	// Send the push request (bilateral)
-	// Can be fire and forget if needed
-	if len(rep.Pull.Versions) > 0 {
-		debug("pushing %d versions back to %s", len(rep.Pull.Versions), peer)
-		push := &pb.PushRequest{
-			Entries: make(map[string]*pb.Entry),
-		}
+			remote := Version{}
+			if pbvers != nil {
+				remote.frompb(pbvers)
+			}
 
-		for key := range rep.Pull.Versions {
-			entry := s.store.GetEntry(key)
-			push.Entries[key] = entry.topb()
-			items++
+			push.Entries[key] = pbentry
+			size += n
+			stale += staleness(entry.Version, remote)
 		}
 
-		reward += 0.25 // add reward for a push request
-
-		if len(rep.Pull.Versions) > 1 {
-			// add reward for multi-items
-			reward += 0.05
+		if len(push.Entries) > 0 {
+			s.push(ctx, client, push, stale, session, metrics)
 		}
 
-		s.syncs[peer].Pushes++
-		pushStart := time.Now()
-		client.Push(context.Background(), push)
-		pushLatency := time.Since(pushStart)
-		s.syncs[peer].Update(pushLatency, "push")
-
-		// add reward for low latency pull requests
-		if pushLatency < 5*time.Millisecond {
-			reward += 0.20 // highest reward for local latencies
-		} else if pushLatency <= 100*time.Millisecond {
-			reward += 0.10 // reward for close by links that don't globe span.
+		if session.Pushed {
+			s.updateSync(func() { metrics.Pushes++ })
 		}
 	}
 
 	// Log anti-entropy success and metrics
-	s.syncs[peer].Syncs++
-	s.syncs[peer].Versions += items
-	info("synchronized %d items to %s", items, peer)
+	items := session.Items()
+	s.updateSync(func() {
+		metrics.Syncs++
+		metrics.Versions += items
+	})
+	infokv("synchronized", "items", items, "peer", peer, "latency", session.Latency())
+}
+
+// push sends a push request to the peer if its bandwidth budget allows it,
+// updating the session and metrics. Returns false if the push was not sent.
+func (s *Server) push(ctx context.Context, client pb.GossipClient, push *pb.PushRequest, stale uint64, session *Synchronization, metrics *SyncStats) bool {
+	if !metrics.bandwidth.Available() {
+		s.updateSync(func() { metrics.Throttled++ })
+		debug("bandwidth budget of %s exhausted during push", session.Peer)
+		return false
+	}
+
+	pushStart := time.Now()
+	rep, err := client.Push(ctx, push)
+	if err != nil {
+		warn(err.Error())
+		return false
+	}
+
+	pushLatency := time.Since(pushStart)
+	session.PushLatency += pushLatency
+	session.PushItems += uint64(len(push.Entries))
+	session.Staleness += stale
+	session.Bytes += uint64(proto.Size(push))
+	session.Pushed = true
+	s.updateSync(func() {
+		metrics.Update(pushLatency, "push")
+		metrics.exchanged(proto.Size(push), proto.Size(rep))
+	})
+	return true
+}
+
+// updateSync modifies the metrics of a peer under lock, since they are read
+// by the metrics endpoints, the status and the features of the bandit while
+// sessions are in flight.
+func (s *Server) updateSync(update func()) {
+	s.Lock()
+	defer s.Unlock()
+	update()
+}
+
+// staleness returns the number of scalar versions that the replica with the
+// stale version was behind the replica with the later version.
+func staleness(later *Version, stale Version) uint64 {
+	if later.Scalar <= stale.Scalar {
+		return 0
+	}
+	return later.Scalar - stale.Scalar
 }
 
 //===========================================================================
@@ -175,6 +382,24 @@ func (s *Server) Pull(ctx context.Context, in *pb.PullRequest) (*pb.PullReply, e
 		},
 	}
 
+	// Compress values with the requested codec if it's known, and request
+	// that the values pushed back are compressed with it too.
+	codec, err := NewCodec(in.Encoding)
+	if err != nil {
+		debug("sending uncompressed values: %s", err)
+	}
+
+	if codec != nil {
+		reply.Pull.Encoding = codec.Name()
+	}
+
+	// Exchange views of the cluster membership if we're replicating
+	if s.bandit != nil {
+		s.membershipChanged(s.members.Merge(in.Members))
+		reply.Members = s.members.topb()
+	}
+
+	var size uint64
 	for key, pbvers := range in.Versions {
 		// Get the remote version
 		version := new(Version)
@@ -204,8 +429,17 @@ func (s *Server) Pull(ctx context.Context, in *pb.PullRequest) (*pb.PullReply, e
 
 		} else if entry != nil && entry.Version.Greater(version) {
 
-			// Local is greater than the remote, send it on.
-			reply.Entries[key] = entry.topb()
+			// Local is greater than the remote, send it on if it is within
+			// the size limit of the reply, otherwise truncate the reply.
+			pbentry := encodeEntry(entry, codec)
+			n := entrySize(key, pbentry)
+			if in.Limit > 0 && len(reply.Entries) > 0 && size+n > in.Limit {
+				reply.Truncated = true
+				continue
+			}
+
+			reply.Entries[key] = pbentry
+			size += n
 
 		}
 
@@ -226,19 +460,8 @@ func (s *Server) Push(ctx context.Context, in *pb.PushRequest) (*pb.PushReply, e
 	reply := &pb.PushReply{Success: false}
 
 	for key, pbent := range in.Entries {
-		entry := new(Entry)
-		entry.frompb(pbent)
-
-		if s.store.PutEntry(key, entry) {
+		if _, ok := s.accept(ctx, key, pbent); ok {
 			reply.Success = true
-
-			// Track visibility if requested
-			if s.visibility != nil && entry.TrackVisibility {
-				s.visibility.Log(key, entry.Version.String())
-				if err := s.visibility.Error(); err != nil {
-					warne(err)
-				}
-			}
 		}
 	}
 
@@ -268,24 +491,33 @@ type SyncStats struct {
 	Pushes      uint64 // Number of successful push exchanges between peers
 	Misses      uint64 // Number of unsuccessful exchanges between peers
 	Versions    uint64 // The total number of object versions exchanged
-	PullLatency *stats.Benchmark
-	PushLatency *stats.Benchmark
-	initialized bool
+	Sent        uint64 // The number of bytes sent to the peer
+	Received    uint64 // The number of bytes received from the peer
+	Throttled   uint64 // Number of exchanges deferred by the bandwidth budget
+	PullLatency *Histogram
+	PushLatency *Histogram
+	LastSync    time.Time     // The time of the last pull exchange with the peer
+	LastLatency time.Duration // The latency of the last pull exchange with the peer
+	writes      uint64        // The number of local writes at the last pull exchange
+	bandwidth   *bandwidth    // The bandwidth budget of the peer (nil if unlimited)
+	unary       bool          // The peer does not implement streaming synchronization
+}
+
+// newSyncStats creates the metrics of a peer with initialized histograms.
+func newSyncStats() *SyncStats {
+	stats := new(SyncStats)
+	stats.Init()
+	return stats
 }
 
 // Init the Syncstats to ensure it's ready for updating.
 func (s *SyncStats) Init() {
-	s.PullLatency = new(stats.Benchmark)
-	s.PushLatency = new(stats.Benchmark)
-	s.initialized = true
+	s.PullLatency = new(Histogram)
+	s.PushLatency = new(Histogram)
 }
 
 // Update the latency of the given type
 func (s *SyncStats) Update(latency time.Duration, method string) error {
-	if !s.initialized {
-		s.Init()
-	}
-
 	switch method {
 	case "pull":
 		s.PullLatency.Update(latency)
@@ -298,19 +530,28 @@ func (s *SyncStats) Update(latency time.Duration, method string) error {
 	return nil
 }
 
+// exchanged counts the bytes of a request sent and its reply received,
+// consuming them from the bandwidth budget.
+func (s *SyncStats) exchanged(sent, received int) {
+	s.Sent += uint64(sent)
+	s.Received += uint64(received)
+	s.bandwidth.Consume(sent + received)
+}
+
 // Serialize the SyncStats to write to disk
 func (s *SyncStats) Serialize() map[string]interface{} {
-	if !s.initialized {
-		s.Init()
-	}
-
 	data := make(map[string]interface{})
 	data["Syncs"] = s.Syncs
 	data["Pulls"] = s.Pulls
 	data["Pushes"] = s.Pushes
 	data["Misses"] = s.Misses
 	data["Versions"] = s.Versions
+	data["Sent"] = s.Sent
+	data["Received"] = s.Received
+	data["Throttled"] = s.Throttled
 	data["PullLatency"] = s.PullLatency.Serialize()
 	data["PushLatency"] = s.PushLatency.Serialize()
+	data["LastSync"] = s.LastSync
+	data["LastLatency"] = s.LastLatency.Seconds()
 	return data
 }
@@ -22,6 +22,7 @@ type Entry struct {
 	Parent          *Version // The version of the parent the entry was derived from
 	Value           []byte   // The data value of the entry
 	TrackVisibility bool     // Whether or not this entry is being tracked
+	Trace           string   // The traceparent of the span that made the entry visible
 	Current         uint64   // The current version scalar
 }
 
@@ -35,6 +36,7 @@ func (e *Entry) topb() *pb.Entry {
 		Version:         e.Version.topb(),
 		Value:           e.Value,
 		TrackVisibility: e.TrackVisibility,
+		Trace:           e.Trace,
 	}
 }
 
@@ -47,6 +49,21 @@ func (e *Entry) frompb(in *pb.Entry) {
 	e.Version.frompb(in.Version)
 	e.Value = in.Value
 	e.TrackVisibility = in.TrackVisibility
+	e.Trace = in.Trace
+}
+
+// copy the entry without its lock. The versions and value are shared since
+// they are replaced rather than modified when the entry is updated.
+func (e *Entry) copy() *Entry {
+	return &Entry{
+		Key:             e.Key,
+		Version:         e.Version,
+		Parent:          e.Parent,
+		Value:           e.Value,
+		TrackVisibility: e.TrackVisibility,
+		Trace:           e.Trace,
+		Current:         e.Current,
+	}
 }
 
 //===========================================================================
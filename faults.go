@@ -0,0 +1,674 @@
+package honu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+//===========================================================================
+// Network Topology
+//===========================================================================
+
+// LoadTopology reads the JSON topology file at the path, which describes the
+// faults of the links between the regions of replicas, e.g.
+//
+//	{
+//	  "epoch": "2018-06-01T12:00:00Z",
+//	  "regions": {
+//	    "umd": ["alia-virginia-35:3264", "alia-virginia-36:3264"],
+//	    "frankfurt": ["alia-frankfurt-31:3264"]
+//	  },
+//	  "default": {"latency": {"mean": "1ms"}},
+//	  "links": [
+//	    {"from": "umd", "to": "frankfurt", "symmetric": true,
+//	     "latency": {"distribution": "normal", "mean": "45ms", "stddev": "5ms"},
+//	     "drop": 0.01, "bandwidth": 1250000}
+//	  ],
+//	  "partitions": [
+//	    {"start": "2m", "duration": "30s", "groups": [["umd"], ["frankfurt"]]}
+//	  ]
+//	}
+func LoadTopology(path string) (*Topology, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	topology := new(Topology)
+	if err := json.Unmarshal(data, topology); err != nil {
+		return nil, fmt.Errorf("could not parse topology %s: %s", path, err)
+	}
+
+	if err := topology.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid topology %s: %s", path, err)
+	}
+	return topology, nil
+}
+
+// ClientEndpoint is the endpoint of the clients of the replicas in the links
+// and partitions of a topology.
+const ClientEndpoint = "clients"
+
+// Topology describes the faults of the network between replicas so that a
+// geo-distributed deployment can be emulated on a single machine. Endpoints
+// of links and partitions are the names of regions, the host:port addresses
+// of replicas that are not in a region, or the clients. Links are directed
+// from the endpoint that makes a request to its peer; the request is delayed
+// by the link to the peer and the reply by the link back from it. Partitions
+// are scheduled from the epoch so that every replica partitions and heals at
+// the same time, or from when each replica is run if there is no epoch.
+type Topology struct {
+	Epoch      *time.Time          `json:"epoch,omitempty"`      // The time partitions are scheduled from
+	Regions    map[string][]string `json:"regions,omitempty"`    // The addresses of the replicas in each region
+	Default    *Link               `json:"default,omitempty"`    // The faults of links that are not specified
+	Links      []*Link             `json:"links,omitempty"`      // The faults of links between endpoints
+	Partitions []*Partition        `json:"partitions,omitempty"` // Scheduled partitions of the endpoints
+}
+
+// Link describes the faults of the messages sent from one endpoint to another.
+type Link struct {
+	From      string  `json:"from,omitempty"`      // The endpoint messages are sent from
+	To        string  `json:"to,omitempty"`        // The endpoint messages are sent to
+	Symmetric bool    `json:"symmetric,omitempty"` // The link back has the same faults
+	Latency   Latency `json:"latency"`             // The one-way delay of each message
+	Drop      float64 `json:"drop,omitempty"`      // The probability that a request is dropped
+	Bandwidth float64 `json:"bandwidth,omitempty"` // Bytes per second shared by messages (0 for unlimited)
+}
+
+// Partition separates groups of endpoints from each other for a duration,
+// starting at an offset from the epoch of the topology or from when the
+// server is run. Endpoints that are not in any group are not partitioned.
+type Partition struct {
+	Start    Duration   `json:"start"`    // The offset from the epoch or when the server is run
+	Duration Duration   `json:"duration"` // How long until the partition heals
+	Groups   [][]string `json:"groups"`   // Endpoints that can only reach their own group
+}
+
+// Validate the probabilities, latencies, endpoints and schedule of the
+// topology.
+func (t *Topology) Validate() error {
+	regions := make(map[string]string)
+	for region, addrs := range t.Regions {
+		if region == ClientEndpoint {
+			return fmt.Errorf("%q cannot be the name of a region", region)
+		}
+
+		for _, addr := range addrs {
+			if other, ok := regions[addr]; ok {
+				return fmt.Errorf("%s cannot be in regions %s and %s", addr, other, region)
+			}
+			regions[addr] = region
+		}
+	}
+
+	links := t.Links
+	if t.Default != nil {
+		links = append([]*Link{t.Default}, links...)
+	}
+
+	for _, link := range links {
+		if link.Drop < 0 || link.Drop > 1 {
+			return fmt.Errorf("drop probability must be in [0, 1], not %f", link.Drop)
+		}
+
+		if link.Bandwidth < 0 {
+			return fmt.Errorf("bandwidth cannot be negative")
+		}
+
+		if err := link.Latency.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, link := range t.Links {
+		if link.From == "" || link.To == "" {
+			return fmt.Errorf("links must specify the endpoints they are from and to")
+		}
+
+		for _, endpoint := range []string{link.From, link.To} {
+			if err := t.validEndpoint(endpoint, regions); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, partition := range t.Partitions {
+		if partition.Start < 0 {
+			return fmt.Errorf("partitions cannot start before the epoch or the server is run")
+		}
+
+		if partition.Duration <= 0 {
+			return fmt.Errorf("partitions must have a positive duration")
+		}
+
+		if len(partition.Groups) < 2 {
+			return fmt.Errorf("partitions must separate at least two groups")
+		}
+
+		for _, group := range partition.Groups {
+			for _, endpoint := range group {
+				if err := t.validEndpoint(endpoint, regions); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validEndpoint returns an error if the endpoint is not a region, the clients
+// or the host:port address of a replica that is not in a region, since the
+// links and partitions of other endpoints would never match a request.
+func (t *Topology) validEndpoint(endpoint string, regions map[string]string) error {
+	if _, ok := t.Regions[endpoint]; ok || endpoint == ClientEndpoint {
+		return nil
+	}
+
+	if region, ok := regions[endpoint]; ok {
+		return fmt.Errorf("%s is in region %s, specify the region instead", endpoint, region)
+	}
+
+	if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		return fmt.Errorf("%s is not a region or the host:port address of a replica", endpoint)
+	}
+	return nil
+}
+
+// endpoint returns the region of the address, or the address itself if it
+// is not in a region.
+func (t *Topology) endpoint(addr string) string {
+	for region, addrs := range t.Regions {
+		for _, member := range addrs {
+			if member == addr {
+				return region
+			}
+		}
+	}
+	return addr
+}
+
+//===========================================================================
+// Latency Distributions
+//===========================================================================
+
+// Latency describes the distribution of the delay of messages: constant,
+// uniform between the min and max, normal with the mean and stddev, or
+// exponential with the mean. If the distribution is not specified, it is
+// normal if a stddev is given, uniform if only a max is, otherwise constant.
+// Sampled delays are bounded by the min and max if they are specified.
+type Latency struct {
+	Distribution string   `json:"distribution,omitempty"`
+	Mean         Duration `json:"mean,omitempty"`
+	Stddev       Duration `json:"stddev,omitempty"`
+	Min          Duration `json:"min,omitempty"`
+	Max          Duration `json:"max,omitempty"`
+}
+
+// Validate the distribution and its parameters.
+func (l Latency) Validate() error {
+	if l.Mean < 0 || l.Stddev < 0 || l.Min < 0 || l.Max < 0 {
+		return fmt.Errorf("latencies cannot be negative")
+	}
+
+	if l.Max > 0 && l.Min > l.Max {
+		return fmt.Errorf("latency min %s is greater than max %s", l.Min, l.Max)
+	}
+
+	switch l.distribution() {
+	case "constant", "normal", "exponential":
+		return nil
+	case "uniform":
+		if l.Max == 0 {
+			return fmt.Errorf("uniform latency requires a max")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown latency distribution %q", l.Distribution)
+	}
+}
+
+// Sample a delay from the distribution.
+func (l Latency) Sample() time.Duration {
+	var delay float64
+	switch l.distribution() {
+	case "uniform":
+		delay = float64(l.Min) + rand.Float64()*float64(l.Max-l.Min)
+	case "normal":
+		delay = float64(l.Mean) + rand.NormFloat64()*float64(l.Stddev)
+	case "exponential":
+		delay = rand.ExpFloat64() * float64(l.Mean)
+	default:
+		delay = float64(l.Mean)
+	}
+
+	delay = math.Max(delay, float64(l.Min))
+	if l.Max > 0 {
+		delay = math.Min(delay, float64(l.Max))
+	}
+	return time.Duration(delay)
+}
+
+// distribution returns the name of the distribution of the latency.
+func (l Latency) distribution() string {
+	if l.Distribution != "" {
+		return strings.ToLower(l.Distribution)
+	}
+
+	switch {
+	case l.Stddev > 0:
+		return "normal"
+	case l.Max > 0 && l.Mean == 0:
+		return "uniform"
+	default:
+		return "constant"
+	}
+}
+
+// Duration is a time.Duration that is parsed from a JSON string such as
+// "45ms", or a number of nanoseconds.
+type Duration time.Duration
+
+// String returns the duration in the format of time.Duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON parses the duration from a string or number of nanoseconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var ns int64
+		if err := json.Unmarshal(data, &ns); err != nil {
+			return fmt.Errorf("could not parse duration %s", data)
+		}
+		*d = Duration(ns)
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+//===========================================================================
+// Fault Injection
+//===========================================================================
+
+// InjectFaults emulates the network described by the topology on the requests
+// the server makes to its peers: anti-entropy, failure detection, membership,
+// hinted handoff and read repair. Every replica loads the same topology and
+// injects the faults of the links from itself, delaying its requests and the
+// replies to them, dropping requests and failing them while partitioned. The
+// requests of clients to the server are injected with the faults of the links
+// from the clients endpoint by the server, since clients do not load the
+// topology. Must be called before Run.
+func (s *Server) InjectFaults(topology *Topology) error {
+	if err := topology.Validate(); err != nil {
+		return err
+	}
+
+	s.faults = &FaultInjector{
+		topology: topology,
+		links:    make(map[string]*linkState),
+		Faults:   make(map[string]*LinkFaults),
+	}
+
+	info(
+		"injecting faults of %d links and %d partitions between %d regions",
+		len(topology.Links), len(topology.Partitions), len(topology.Regions),
+	)
+	return nil
+}
+
+// FaultInjector delays, drops and partitions the requests the replica makes
+// to its peers according to the topology. The faults are injected by client
+// interceptors on the connections to each peer, and into the requests of
+// clients by a server interceptor.
+type FaultInjector struct {
+	sync.Mutex
+	Faults   map[string]*LinkFaults // The faults injected on the link to each peer and the clients
+	topology *Topology              // The links and partitions of the network
+	local    string                 // The endpoint of the local replica
+	started  time.Time              // The time partitions are scheduled from
+	links    map[string]*linkState  // The state of the bandwidth of each link
+	timers   []*time.Timer          // Log when partitions start and heal
+}
+
+// LinkFaults counts the faults injected on the requests to a peer.
+type LinkFaults struct {
+	Requests    uint64        // The number of requests and stream messages sent
+	Dropped     uint64        // The number of requests that were dropped
+	Partitioned uint64        // The number of requests that failed during partitions
+	Delay       time.Duration // The total delay injected into requests and replies
+}
+
+// linkState tracks when the bandwidth of a directed link is available.
+type linkState struct {
+	link *Link
+	busy time.Time // The time the link finishes transmitting queued messages
+}
+
+// Start scheduling partitions of the local replica from the epoch of the
+// topology or from now, logging when they start and heal. Partitions that
+// healed before the replica was run are not logged.
+func (f *FaultInjector) Start(local string) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.local = f.topology.endpoint(local)
+	f.started = time.Now()
+	if f.topology.Epoch != nil {
+		f.started = *f.topology.Epoch
+	}
+
+	for _, partition := range f.topology.Partitions {
+		partition := partition
+		starts := f.started.Add(time.Duration(partition.Start))
+		heals := starts.Add(time.Duration(partition.Duration))
+		if !heals.After(time.Now()) {
+			continue
+		}
+
+		f.timers = append(
+			f.timers,
+			time.AfterFunc(time.Until(starts), func() {
+				info("partition started, separating %v until %s", partition.Groups, heals.Format(time.RFC3339))
+			}),
+			time.AfterFunc(time.Until(heals), func() {
+				info("partition healed, reconnecting %v", partition.Groups)
+			}),
+		)
+	}
+}
+
+// Stop logging the partitions that have not started or healed yet.
+func (f *FaultInjector) Stop() {
+	f.Lock()
+	defer f.Unlock()
+
+	for _, timer := range f.timers {
+		timer.Stop()
+	}
+	f.timers = nil
+}
+
+// UnaryInterceptor returns an interceptor that injects the faults of the
+// links to and from the peer into its unary requests.
+func (f *FaultInjector) UnaryInterceptor(peer string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := f.send(ctx, peer, false, req); err != nil {
+			return err
+		}
+
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			return err
+		}
+
+		return f.receive(ctx, peer, false, reply)
+	}
+}
+
+// StreamInterceptor returns an interceptor that injects the faults of the
+// links to and from the peer into every message of its streams.
+func (f *FaultInjector) StreamInterceptor(peer string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if err := f.partitioned(peer); err != nil {
+			return nil, err
+		}
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &faultyStream{ClientStream: stream, faults: f, peer: peer}, nil
+	}
+}
+
+// StorageInterceptor injects the faults of the links between the clients and
+// the replica into the Storage requests of clients: the request is delayed by
+// the link from the clients and the reply by the link back to them. Requests
+// of peers are not affected since the peers inject their own faults.
+func (f *FaultInjector) StorageInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, "/rpc.Storage/") {
+		return handler(ctx, req)
+	}
+
+	if err := f.send(ctx, ClientEndpoint, true, req); err != nil {
+		return nil, err
+	}
+
+	reply, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.receive(ctx, ClientEndpoint, true, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Serialize the faults injected on the link to each peer
+func (f *FaultInjector) Serialize() map[string]interface{} {
+	f.Lock()
+	defer f.Unlock()
+
+	data := make(map[string]interface{})
+	for peer, faults := range f.Faults {
+		data[peer] = map[string]interface{}{
+			"requests":    faults.Requests,
+			"dropped":     faults.Dropped,
+			"partitioned": faults.Partitioned,
+			"delay":       faults.Delay.Seconds(),
+		}
+	}
+	return data
+}
+
+// send injects the faults of the link to the peer into a request, or of the
+// link from the peer if the request is inbound: it fails if the peer is
+// partitioned or the request is dropped, otherwise it is delayed by the
+// latency and bandwidth of the link.
+func (f *FaultInjector) send(ctx context.Context, peer string, inbound bool, msg interface{}) error {
+	if err := f.partitioned(peer); err != nil {
+		return err
+	}
+
+	f.Lock()
+	from, to := f.local, f.topology.endpoint(peer)
+	if inbound {
+		from, to = to, from
+	}
+
+	link := f.link(from, to)
+	faults := f.faults(peer)
+	faults.Requests++
+
+	if link.link.Drop > 0 && rand.Float64() < link.link.Drop {
+		faults.Dropped++
+		f.Unlock()
+		return grpc.Errorf(codes.Unavailable, "request to %s was dropped", peer)
+	}
+
+	delay := f.delay(link, msg)
+	faults.Delay += delay
+	f.Unlock()
+
+	return sleep(ctx, delay)
+}
+
+// receive delays a reply from the peer by the link back from it, or a reply
+// to the peer by the link to it if the request was inbound.
+func (f *FaultInjector) receive(ctx context.Context, peer string, inbound bool, msg interface{}) error {
+	f.Lock()
+	from, to := f.topology.endpoint(peer), f.local
+	if inbound {
+		from, to = to, from
+	}
+
+	link := f.link(from, to)
+	delay := f.delay(link, msg)
+	f.faults(peer).Delay += delay
+	f.Unlock()
+
+	return sleep(ctx, delay)
+}
+
+// partitioned returns an error if the peer is partitioned from the replica.
+func (f *FaultInjector) partitioned(peer string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	remote := f.topology.endpoint(peer)
+	elapsed := time.Since(f.started)
+
+	for _, partition := range f.topology.Partitions {
+		if elapsed < time.Duration(partition.Start) || elapsed >= time.Duration(partition.Start+partition.Duration) {
+			continue
+		}
+
+		local, other := group(partition.Groups, f.local), group(partition.Groups, remote)
+		if local >= 0 && other >= 0 && local != other {
+			f.faults(peer).Partitioned++
+			return grpc.Errorf(codes.Unavailable, "%s is partitioned from %s", peer, f.local)
+		}
+	}
+
+	return nil
+}
+
+// link returns the state of the directed link between the endpoints, which
+// has the faults of the first link that matches it, or the default.
+// Must be called under lock.
+func (f *FaultInjector) link(from, to string) *linkState {
+	key := from + "->" + to
+	if state, ok := f.links[key]; ok {
+		return state
+	}
+
+	state := &linkState{link: f.topology.Default}
+	for _, link := range f.topology.Links {
+		if (link.From == from && link.To == to) || (link.Symmetric && link.From == to && link.To == from) {
+			state.link = link
+			break
+		}
+	}
+
+	if state.link == nil {
+		state.link = new(Link)
+	}
+
+	f.links[key] = state
+	return state
+}
+
+// delay samples the latency of the link and adds the time to transmit the
+// message once the messages queued before it have been transmitted, if the
+// bandwidth of the link is limited. Must be called under lock.
+func (f *FaultInjector) delay(state *linkState, msg interface{}) time.Duration {
+	delay := state.link.Latency.Sample()
+	if state.link.Bandwidth <= 0 {
+		return delay
+	}
+
+	var size int
+	if pbmsg, ok := msg.(proto.Message); ok {
+		size = proto.Size(pbmsg)
+	}
+
+	now := time.Now()
+	if state.busy.Before(now) {
+		state.busy = now
+	}
+
+	transmit := time.Duration(float64(size) / state.link.Bandwidth * float64(time.Second))
+	state.busy = state.busy.Add(transmit)
+	return delay + state.busy.Sub(now)
+}
+
+// faults returns the faults injected on the link to the peer.
+// Must be called under lock.
+func (f *FaultInjector) faults(peer string) *LinkFaults {
+	faults, ok := f.Faults[peer]
+	if !ok {
+		faults = new(LinkFaults)
+		f.Faults[peer] = faults
+	}
+	return faults
+}
+
+// group returns the index of the group the endpoint is in, or -1.
+func group(groups [][]string, endpoint string) int {
+	for idx, members := range groups {
+		for _, member := range members {
+			if member == endpoint {
+				return idx
+			}
+		}
+	}
+	return -1
+}
+
+// sleep for the delay unless the context is done first.
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// faultyStream injects the faults of the links to and from the peer into
+// every message sent and received on a client stream.
+type faultyStream struct {
+	grpc.ClientStream
+	faults *FaultInjector
+	peer   string
+}
+
+// SendMsg sends the message after the faults of the link to the peer.
+func (s *faultyStream) SendMsg(m interface{}) error {
+	if err := s.faults.send(s.Context(), s.peer, false, m); err != nil {
+		return err
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+// RecvMsg receives a message and delays it by the link back from the peer.
+func (s *faultyStream) RecvMsg(m interface{}) error {
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.faults.receive(s.Context(), s.peer, false, m)
+}
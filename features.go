@@ -0,0 +1,62 @@
+package honu
+
+import (
+	"time"
+)
+
+// PeerFeatures are the names of the context features extracted for each
+// peer before it is selected by a contextual bandit. All features are scaled
+// to [0, 1] and the first feature is the bias.
+var PeerFeatures = []string{"bias", "since", "latency", "writes", "region"}
+
+// Scales of the peer features, each feature is one half at its scale.
+const (
+	writesScale  = 10
+	latencyScale = 100 * time.Millisecond
+	sinceScale   = 10 // multiples of the anti-entropy delay
+)
+
+// Region sets the label of the location of the replica, which is gossiped to
+// peers so that contextual bandits can prefer peers in the same region.
+func (s *Server) Region(label string) {
+	s.members.Locate(label)
+}
+
+// context sets the features of every peer on a contextual bandit from the
+// synchronization statistics and membership. Must be called under lock.
+func (s *Server) context(bandit ContextualBandit) {
+	for arm, peer := range s.peers {
+		bandit.Context(arm, s.features(peer))
+	}
+}
+
+// features extracts the context of the peer: the time since the last sync
+// with the peer, its last observed latency, the number of keys written
+// locally since the last sync and if it's in the same region as the local
+// replica. Must be called under lock.
+func (s *Server) features(peer string) []float64 {
+	features := make([]float64, len(PeerFeatures))
+	features[0] = 1
+
+	metrics := s.syncs[peer]
+	if metrics == nil || metrics.LastSync.IsZero() {
+		// Peers that have never been synchronized are as stale as possible
+		features[1] = 1
+		features[3] = saturate(float64(s.writes), writesScale)
+	} else {
+		since := time.Since(metrics.LastSync)
+		features[1] = saturate(float64(since), float64(sinceScale*s.delay))
+		features[3] = saturate(float64(s.writes-metrics.writes), writesScale)
+	}
+
+	if metrics != nil {
+		features[2] = saturate(float64(metrics.LastLatency), float64(latencyScale))
+	}
+
+	local := s.members.Region()
+	if member, ok := s.members.Get(peer); ok && local != "" && member.Region == local {
+		features[4] = 1
+	}
+
+	return features
+}
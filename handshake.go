@@ -0,0 +1,97 @@
+package honu
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	pb "github.com/bbengfort/honu/rpc"
+)
+
+// Errors returned by a handshake when the peer is not a distinct replica,
+// e.g. because the peer list contains an address of the local replica or an
+// address of a member other than the one that it advertises.
+var (
+	ErrSelfConnection  = errors.New("connected to the local replica")
+	ErrAliasConnection = errors.New("connected to a replica known by another address")
+)
+
+//===========================================================================
+// Peer Identity
+//===========================================================================
+
+// Cluster sets the identifier of the cluster that the server belongs to.
+// Peers exchange their cluster id on handshake and connections between
+// replicas of different clusters are refused.
+func (s *Server) Cluster(id string) {
+	s.cluster = id
+}
+
+// handshake establishes the identity of the peer on the connection if it has
+// not been verified yet. If the peer is the local replica or an alias of a
+// known member, it is dropped from the peer set and ErrSelfConnection or
+// ErrAliasConnection is returned. If the peer belongs to another cluster, it
+// is dropped from the peer set and an error is returned.
+func (s *Server) handshake(conn *grpc.ClientConn, peer string) error {
+	if member, ok := s.members.Get(peer); ok && member.Verified {
+		return nil
+	}
+
+	req := &pb.HandshakeRequest{
+		Pid:     s.members.local.PID,
+		Cluster: s.cluster,
+		Addr:    s.members.Local(),
+	}
+
+	rep, err := pb.NewGossipClient(conn).Handshake(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("could not handshake with %s: %s", peer, err)
+	}
+
+	if rep.Cluster != s.cluster || !rep.Success {
+		s.members.Ignore(peer)
+		s.membershipChanged([]Member{{Addr: peer, Status: MemberLeft}})
+		return fmt.Errorf(
+			"refused connection to %s: peer is in cluster %q not %q",
+			peer, rep.Cluster, s.cluster,
+		)
+	}
+
+	if !s.members.Verify(peer, rep.Pid) {
+		s.membershipChanged([]Member{{Addr: peer, Status: MemberLeft}})
+		if rep.Pid == s.members.local.PID {
+			info("dropped %s from peers: address of the local replica", peer)
+			return ErrSelfConnection
+		}
+
+		info("dropped %s from peers: alias of replica %d", peer, rep.Pid)
+		return ErrAliasConnection
+	}
+
+	debug("verified %s is replica %d in cluster %q", peer, rep.Pid, rep.Cluster)
+	return nil
+}
+
+//===========================================================================
+// Server Identity RPC methods
+//===========================================================================
+
+// Handshake replies with the PID and cluster id of the local replica,
+// refusing the connection if the requester belongs to a different cluster.
+func (s *Server) Handshake(ctx context.Context, in *pb.HandshakeRequest) (*pb.HandshakeReply, error) {
+	reply := &pb.HandshakeReply{
+		Success: true,
+		Pid:     s.members.local.PID,
+		Cluster: s.cluster,
+	}
+
+	if in.Cluster != s.cluster {
+		reply.Success = false
+		reply.Error = fmt.Sprintf("replica is in cluster %q not %q", s.cluster, in.Cluster)
+		warn("refused handshake from %s (replica %d): %s", in.Addr, in.Pid, reply.Error)
+	}
+
+	return reply, nil
+}
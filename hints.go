@@ -0,0 +1,332 @@
+package honu
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	pb "github.com/bbengfort/honu/rpc"
+)
+
+//===========================================================================
+// Eager Push with Hinted Handoff
+//===========================================================================
+
+// HintedHandoff pushes every local write eagerly to the peers, in addition
+// to anti-entropy. Writes that cannot be delivered because a peer is dead or
+// unreachable are stored locally as hints, at most one per key and up to the
+// limit for each peer, and are replayed once the peer is reachable again:
+// when it is alive again or an anti-entropy session with it succeeds. Hints
+// beyond the limit are dropped; the peer catches up on them by anti-entropy.
+type HintedHandoff struct {
+	sync.Mutex
+	Limit    int                         // The maximum number of hints stored for each peer (none if zero)
+	Pushed   uint64                      // The number of writes pushed eagerly to peers
+	Stored   uint64                      // The number of hints stored for unreachable peers
+	Dropped  uint64                      // The number of hints dropped because the limit was reached
+	Replayed uint64                      // The number of hints delivered to peers
+	Latency  *Histogram                  // The time from storing hints until they were replayed
+	server   *Server                     // The server whose writes are being pushed
+	hints    map[string]map[string]*hint // Pending hints by peer and key
+	replays  map[string]bool             // Peers whose hints are being replayed
+	conns    map[string]*grpc.ClientConn // Connections to peers for eager pushes
+}
+
+// hint is a write that could not be delivered to a peer.
+type hint struct {
+	entry  *pb.Entry // The entry that was written
+	stored time.Time // When the write could first not be delivered
+}
+
+// EagerPush configures the server to push every write to its peers, storing
+// up to the specified number of hints for each unreachable peer.
+func (s *Server) EagerPush(hints int) {
+	s.handoff = &HintedHandoff{
+		Limit:   hints,
+		Latency: new(Histogram),
+		server:  s,
+		hints:   make(map[string]map[string]*hint),
+		replays: make(map[string]bool),
+		conns:   make(map[string]*grpc.ClientConn),
+	}
+
+	info("pushing writes eagerly with up to %d hints per peer", hints)
+}
+
+// Write pushes the latest entry of the key to every peer, storing hints for
+// dead peers, unreachable peers and peers that already have pending hints.
+func (h *HintedHandoff) Write(key string) {
+	s := h.server
+	if s.isStopped() {
+		return
+	}
+
+	s.store.RLock()
+	entry := s.store.GetEntry(key)
+	if entry == nil {
+		s.store.RUnlock()
+		return
+	}
+	entry = entry.copy()
+	s.store.RUnlock()
+
+	s.Lock()
+	peers := make([]string, len(s.peers))
+	copy(peers, s.peers)
+	s.Unlock()
+
+	now := time.Now()
+	for _, peer := range peers {
+		member, _ := s.members.Get(peer)
+		pbentry := encodeEntry(entry, s.pushCodec(peer))
+
+		h.Lock()
+		if member.Status == MemberDead || len(h.hints[peer]) > 0 {
+			h.hint(peer, key, pbentry, now)
+			h.Unlock()
+			continue
+		}
+		h.Unlock()
+
+		go func(peer string) {
+			entries := map[string]*pb.Entry{key: pbentry}
+			err := h.push(peer, entries)
+
+			h.Lock()
+			defer h.Unlock()
+
+			if err != nil {
+				debug("could not push %s to %s: %s", key, peer, err)
+				h.hint(peer, key, pbentry, now)
+				return
+			}
+			h.Pushed++
+		}(peer)
+	}
+}
+
+// Replay the hints of the peer in messages within the message limit. Hints
+// that could not be delivered are stored again unless a later write to the
+// key has been hinted in the meantime.
+func (h *HintedHandoff) Replay(peer string) {
+	if h.server.isStopped() {
+		return
+	}
+
+	h.Lock()
+	hints := h.hints[peer]
+	if len(hints) == 0 || h.replays[peer] {
+		h.Unlock()
+		return
+	}
+
+	delete(h.hints, peer)
+	h.replays[peer] = true
+	h.Unlock()
+
+	defer func() {
+		h.Lock()
+		delete(h.replays, peer)
+		h.Unlock()
+	}()
+
+	var size uint64
+	var replayed int
+	limit := h.server.limit
+	batch := make(map[string]*pb.Entry)
+	pending := make(map[string]*hint)
+
+	flush := func() bool {
+		if h.server.isStopped() {
+			return false
+		}
+
+		if err := h.push(peer, batch); err != nil {
+			debug("could not replay hints to %s: %s", peer, err)
+			return false
+		}
+
+		now := time.Now()
+		h.Lock()
+		for _, hint := range pending {
+			h.Replayed++
+			h.Latency.Update(now.Sub(hint.stored))
+		}
+		h.Unlock()
+
+		replayed += len(pending)
+		batch = make(map[string]*pb.Entry)
+		pending = make(map[string]*hint)
+		size = 0
+		return true
+	}
+
+	for key, hint := range hints {
+		n := entrySize(key, hint.entry)
+		if limit > 0 && len(batch) > 0 && size+n > limit {
+			if !flush() {
+				break
+			}
+		}
+
+		batch[key] = hint.entry
+		pending[key] = hint
+		delete(hints, key)
+		size += n
+	}
+
+	if len(batch) > 0 {
+		flush()
+	}
+
+	// Store the hints that were not delivered again
+	h.Lock()
+	for _, undelivered := range []map[string]*hint{pending, hints} {
+		for key, item := range undelivered {
+			if _, ok := h.hints[peer]; !ok {
+				h.hints[peer] = make(map[string]*hint)
+			}
+
+			if _, ok := h.hints[peer][key]; !ok {
+				h.hints[peer][key] = item
+			}
+		}
+	}
+	h.Unlock()
+
+	if replayed > 0 {
+		infokv("replayed hints", "hints", replayed, "peer", peer)
+	}
+}
+
+// Forget the hints of a peer that has left the cluster.
+func (h *HintedHandoff) Forget(peer string) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.Dropped += uint64(len(h.hints[peer]))
+	delete(h.hints, peer)
+
+	if conn, ok := h.conns[peer]; ok {
+		conn.Close()
+		delete(h.conns, peer)
+	}
+}
+
+// Close the connections to the peers.
+func (h *HintedHandoff) Close() {
+	h.Lock()
+	defer h.Unlock()
+
+	for peer, conn := range h.conns {
+		conn.Close()
+		delete(h.conns, peer)
+	}
+}
+
+// Serialize the hinted handoff metrics to write to disk
+func (h *HintedHandoff) Serialize() map[string]interface{} {
+	h.Lock()
+	defer h.Unlock()
+
+	pending := make(map[string]int)
+	for peer, hints := range h.hints {
+		pending[peer] = len(hints)
+	}
+
+	data := make(map[string]interface{})
+	data["Limit"] = h.Limit
+	data["Pushed"] = h.Pushed
+	data["Stored"] = h.Stored
+	data["Dropped"] = h.Dropped
+	data["Replayed"] = h.Replayed
+	data["Pending"] = pending
+	data["ReplayLatency"] = h.Latency.Serialize()
+	return data
+}
+
+// hint stores the write for the peer, replacing an earlier version of the key
+// but keeping the time it was first stored. Must be called under lock.
+func (h *HintedHandoff) hint(peer, key string, entry *pb.Entry, stored time.Time) {
+	hints, ok := h.hints[peer]
+	if !ok {
+		hints = make(map[string]*hint)
+		h.hints[peer] = hints
+	}
+
+	if prev, ok := hints[key]; ok {
+		var version, previous Version
+		version.frompb(entry.Version)
+		previous.frompb(prev.entry.Version)
+
+		if version.Greater(&previous) {
+			prev.entry = entry
+		}
+		return
+	}
+
+	if len(hints) >= h.Limit {
+		h.Dropped++
+		return
+	}
+
+	hints[key] = &hint{entry: entry, stored: stored}
+	h.Stored++
+}
+
+// push the entries to the peer, connecting to it if necessary. The
+// connection is closed if the push fails so that it is redialed. Pushes are
+// canceled when the server shuts down.
+func (h *HintedHandoff) push(peer string, entries map[string]*pb.Entry) error {
+	ctx := h.server.runContext()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	h.Lock()
+	conn, ok := h.conns[peer]
+	h.Unlock()
+
+	if !ok {
+		var err error
+		conn, err = h.server.dial(ctx, peer)
+		if err != nil {
+			return err
+		}
+
+		// Do not keep connections dialed while the server shut down
+		h.Lock()
+		if err := ctx.Err(); err != nil {
+			h.Unlock()
+			conn.Close()
+			return err
+		}
+
+		if prev, ok := h.conns[peer]; ok {
+			conn.Close()
+			conn = prev
+		} else {
+			h.conns[peer] = conn
+		}
+		h.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := pb.NewGossipClient(conn)
+	if _, err := client.Push(ctx, &pb.PushRequest{Entries: entries}); err != nil {
+		h.Lock()
+		if h.conns[peer] == conn {
+			delete(h.conns, peer)
+		}
+		h.Unlock()
+
+		conn.Close()
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package honu
+
+import (
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/bbengfort/x/stats"
+)
+
+// HistogramPrecision is the number of bits of each latency that are kept
+// when it is bucketed: every power of two is divided into 2^precision
+// buckets, so percentiles are within 1/2^precision (~1.6%) of the latencies
+// that were recorded, whatever their magnitude.
+const HistogramPrecision = 6
+
+// Percentiles are the percentiles of latencies reported in results.
+var Percentiles = []struct {
+	Name string
+	Rank float64
+}{
+	{"p50", 0.50}, {"p90", 0.90}, {"p95", 0.95}, {"p99", 0.99}, {"p999", 0.999},
+}
+
+// Histogram records latencies in log-linear buckets in addition to the
+// descriptive statistics of a benchmark, so that percentiles can be reported
+// without keeping every latency. Like benchmarks, histograms can be merged,
+// e.g. to combine the latencies of several clients. As with benchmarks, a
+// zero latency is counted as a timeout and is not bucketed.
+type Histogram struct {
+	stats.Benchmark
+	mu      sync.RWMutex // Guards the buckets separately from the benchmark
+	buckets []uint64     // Number of latencies in each bucket, grown as needed
+	count   uint64       // Number of latencies that were bucketed
+}
+
+// Timings are the histograms of the latencies of several operations, keyed
+// by the name of the operation.
+type Timings map[string]*Histogram
+
+// Serialize the timings to save to JSON format.
+func (t Timings) Serialize() map[string]interface{} {
+	data := make(map[string]interface{})
+	for name, hist := range t {
+		data[name] = hist.Serialize()
+	}
+	return data
+}
+
+// Update the histogram with one or more latencies (thread-safe).
+func (h *Histogram) Update(durations ...time.Duration) {
+	h.Benchmark.Update(durations...)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, duration := range durations {
+		if duration > 0 {
+			h.bucket(bucketIndex(duration), 1)
+		}
+	}
+}
+
+// Append another histogram to the histogram, merging its latencies.
+func (h *Histogram) Append(o *Histogram) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.Benchmark.Append(&o.Benchmark)
+	for idx, count := range o.buckets {
+		if count > 0 {
+			h.bucket(idx, count)
+		}
+	}
+}
+
+// Percentile returns the latency below which the fraction p of the latencies
+// in the histogram fall, within the precision of the buckets. If no latencies
+// have been recorded, a zero duration is returned.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.percentile(p)
+}
+
+// Serialize returns the descriptive statistics of the benchmark along with
+// the percentiles of the latencies, as human readable durations.
+func (h *Histogram) Serialize() map[string]interface{} {
+	data := h.Benchmark.Serialize()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	data["timeouts"] = h.Benchmark.Timeouts()
+	for _, pct := range Percentiles {
+		data[pct.Name] = h.percentile(pct.Rank).String()
+	}
+	return data
+}
+
+// percentile is the unlocked implementation of Percentile. The latency of a
+// bucket is its midpoint, bounded by the extrema of the benchmark.
+func (h *Histogram) percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := uint64(p*float64(h.count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+
+	var cumulative uint64
+	for idx, count := range h.buckets {
+		cumulative += count
+		if cumulative >= rank {
+			latency := bucketValue(idx)
+			if fastest := h.Benchmark.Fastest(); latency < fastest {
+				latency = fastest
+			}
+			if slowest := h.Benchmark.Slowest(); latency > slowest {
+				latency = slowest
+			}
+			return latency
+		}
+	}
+
+	return h.Benchmark.Slowest()
+}
+
+// bucket adds the count to the bucket, growing the buckets if necessary.
+// Must be called under lock.
+func (h *Histogram) bucket(idx int, count uint64) {
+	if idx >= len(h.buckets) {
+		buckets := make([]uint64, idx+1)
+		copy(buckets, h.buckets)
+		h.buckets = buckets
+	}
+
+	h.buckets[idx] += count
+	h.count += count
+}
+
+// bucketIndex returns the bucket of a latency: latencies below 2^precision
+// nanoseconds have a bucket each, larger latencies are bucketed by their
+// power of two and their most significant bits below it.
+func bucketIndex(latency time.Duration) int {
+	ns := uint64(latency)
+	if ns < 1<<HistogramPrecision {
+		return int(ns)
+	}
+
+	shift := uint(bits.Len64(ns)) - 1 - HistogramPrecision
+	return int(shift+1)<<HistogramPrecision + int(ns>>shift) - 1<<HistogramPrecision
+}
+
+// bucketValue returns the midpoint latency of the bucket.
+func bucketValue(idx int) time.Duration {
+	if idx < 1<<HistogramPrecision {
+		return time.Duration(idx)
+	}
+
+	shift := uint(idx>>HistogramPrecision) - 1
+	lower := uint64(idx&(1<<HistogramPrecision-1)+1<<HistogramPrecision) << shift
+	return time.Duration(lower + (uint64(1)<<shift)/2)
+}
@@ -1,5 +1,11 @@
 package honu
 
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+)
+
 //===========================================================================
 // Version Chain and Consistency Analysis
 //===========================================================================
@@ -18,6 +24,7 @@ type VersionNode struct {
 // versions in a single array, serializing appends via a channel that allows
 // multiple go routines to stream version information to the history.
 type History struct {
+	sync.RWMutex
 	versions []*VersionNode    // The array of version tree nodes in the chain
 	queue    chan *VersionNode // The queue of entries to ad to the history
 }
@@ -34,7 +41,9 @@ func (h *History) Run() {
 	go func() {
 		for {
 			node := <-h.queue
+			h.Lock()
 			h.versions = append(h.versions, node)
+			h.Unlock()
 		}
 	}()
 }
@@ -48,3 +57,32 @@ func (h *History) Append(key *string, parent, version *Version) {
 	}
 	h.queue <- node
 }
+
+// Length returns the number of versions in the history.
+func (h *History) Length() uint64 {
+	h.RLock()
+	defer h.RUnlock()
+	return uint64(len(h.versions))
+}
+
+// Snapshot writes the versions in the history to the path as a JSON array
+// of the key, parent and version of each version in the order it was seen.
+func (h *History) Snapshot(path string) error {
+	h.RLock()
+	nodes := make([]map[string]string, 0, len(h.versions))
+	for _, node := range h.versions {
+		nodes = append(nodes, map[string]string{
+			"key":     *node.Key,
+			"parent":  node.Parent.String(),
+			"version": node.Version.String(),
+		})
+	}
+	h.RUnlock()
+
+	data, err := json.Marshal(nodes)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
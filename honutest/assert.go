@@ -0,0 +1,46 @@
+package honutest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bbengfort/honu"
+)
+
+//===========================================================================
+// Assertions
+//===========================================================================
+
+// AssertConverged fails the test if the replicas of the cluster do not all
+// hold identical views before the timeout, reporting the keys that differ.
+// Returns the views of the replicas.
+func AssertConverged(t testing.TB, c *Cluster, timeout time.Duration) []map[string]honu.Version {
+	t.Helper()
+
+	views, err := c.WaitForConvergence(timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return views
+}
+
+// AssertVisible fails the test if the version of the key, or a later one, is
+// not visible on every replica of the cluster before the timeout.
+func AssertVisible(t testing.TB, c *Cluster, key, version string, timeout time.Duration) []map[string]honu.Version {
+	t.Helper()
+
+	views, err := c.WaitForVersion(key, version, timeout)
+	if err != nil {
+		t.Fatalf("version %s of %s is not visible: %s", version, key, err)
+	}
+	return views
+}
+
+// AssertIdentical fails the test immediately if the views differ.
+func AssertIdentical(t testing.TB, views []map[string]honu.Version) {
+	t.Helper()
+
+	if !Identical(views) {
+		t.Fatalf("views are not identical: %s", Diff(views))
+	}
+}
@@ -0,0 +1,408 @@
+package honutest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbengfort/honu"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	pb "github.com/bbengfort/honu/rpc"
+)
+
+// Defaults of the replicas of a cluster, with a short anti-entropy delay so
+// that tests converge quickly.
+const (
+	DefaultDelay  = 50 * time.Millisecond
+	DefaultBandit = "uniform"
+)
+
+// ConvergencePoll is the interval at which the views of the replicas are
+// compared while waiting for convergence.
+var ConvergencePoll = 10 * time.Millisecond
+
+// timeout of connections and requests to the replicas of the cluster.
+const timeout = 5 * time.Second
+
+//===========================================================================
+// Cluster Configuration
+//===========================================================================
+
+// Config describes the replicas of a cluster. Replicas are identified by
+// their index in the cluster; replica i has process id i+1.
+type Config struct {
+	Replicas   int                                      // The number of replicas in the cluster
+	Sequential bool                                     // Relax the stores to sequential consistency
+	Bandit     string                                   // Peer selection bandit strategy (uniform by default)
+	Options    honu.BanditOptions                       // Parameters of the bandit strategy
+	Delay      time.Duration                            // The anti-entropy delay (DefaultDelay by default)
+	Peers      map[int][]int                            // The peers of each replica by index (all other replicas by default)
+	Configure  func(idx int, server *honu.Server) error // Configures each replica before it is run
+}
+
+// peers returns the indices of the peers of the replica.
+func (conf Config) peers(idx int) []int {
+	if peers, ok := conf.Peers[idx]; ok {
+		return peers
+	}
+
+	peers := make([]int, 0, conf.Replicas-1)
+	for i := 0; i < conf.Replicas; i++ {
+		if i != idx {
+			peers = append(peers, i)
+		}
+	}
+	return peers
+}
+
+//===========================================================================
+// Cluster
+//===========================================================================
+
+// NewCluster creates the replicas of the cluster described by the config and
+// connects them on an in-memory network. The replicas are not run until the
+// cluster is started.
+func NewCluster(conf Config) (*Cluster, error) {
+	if conf.Replicas < 1 {
+		return nil, errors.New("a cluster requires at least one replica")
+	}
+
+	if conf.Bandit == "" {
+		conf.Bandit = DefaultBandit
+	}
+
+	if conf.Delay == 0 {
+		conf.Delay = DefaultDelay
+	}
+
+	c := &Cluster{
+		Network: NewNetwork(),
+		Servers: make([]*honu.Server, conf.Replicas),
+		Addrs:   make([]string, conf.Replicas),
+		conns:   make(map[int]*grpc.ClientConn),
+	}
+
+	for idx := range c.Servers {
+		c.Addrs[idx] = fmt.Sprintf("replica%d", idx+1)
+	}
+
+	for idx := range c.Servers {
+		server := honu.NewServer(uint64(idx+1), conf.Sequential)
+		server.DialPeersWith(c.Network.Dialer(c.Addrs[idx]))
+
+		if conf.Replicas > 1 {
+			var peers []string
+			for _, peer := range conf.peers(idx) {
+				if peer < 0 || peer >= conf.Replicas || peer == idx {
+					return nil, fmt.Errorf("replica %d cannot have replica %d as a peer", idx, peer)
+				}
+				peers = append(peers, c.Addrs[peer])
+			}
+
+			if err := server.Replicate(peers, conf.Delay, conf.Bandit, conf.Options); err != nil {
+				return nil, err
+			}
+		}
+
+		if conf.Configure != nil {
+			if err := conf.Configure(idx, server); err != nil {
+				return nil, err
+			}
+		}
+
+		c.Servers[idx] = server
+	}
+
+	return c, nil
+}
+
+// Cluster is a set of replicas that run in the same process and synchronize
+// with each other over an in-memory network.
+type Cluster struct {
+	sync.Mutex
+	Network *Network       // The in-memory network the replicas are connected on
+	Servers []*honu.Server // The replicas of the cluster by index
+	Addrs   []string       // The addresses of the replicas on the network
+	cancel  context.CancelFunc
+	errs    []chan error
+	conns   map[int]*grpc.ClientConn // Connections to the replicas to access them
+}
+
+// Start running the replicas, returning once all of them are serving.
+func (c *Cluster) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.errs = make([]chan error, len(c.Servers))
+
+	for idx, server := range c.Servers {
+		lis, err := c.Network.Listen(c.Addrs[idx])
+		if err != nil {
+			c.Stop()
+			return err
+		}
+
+		c.errs[idx] = make(chan error, 1)
+		go func(server *honu.Server, errs chan<- error) {
+			errs <- server.Serve(ctx, lis)
+		}(server, c.errs[idx])
+	}
+
+	// Wait until every replica accepts requests
+	for idx := range c.Servers {
+		if _, err := c.conn(idx); err != nil {
+			c.Stop()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stop all replicas of the cluster, waiting until they have shut down, and
+// return the first error that a replica stopped with.
+func (c *Cluster) Stop() error {
+	c.Lock()
+	for idx, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, idx)
+	}
+	c.Unlock()
+
+	if c.cancel == nil {
+		return nil
+	}
+	c.cancel()
+
+	var err error
+	for idx, errs := range c.errs {
+		if errs == nil {
+			continue
+		}
+
+		if serr := <-errs; serr != nil && err == nil {
+			err = fmt.Errorf("replica %d: %s", idx, serr)
+		}
+		c.errs[idx] = nil
+	}
+	return err
+}
+
+// Partition the replicas into groups by index, so that replicas only
+// synchronize with replicas in their own group until the cluster is healed.
+// Replicas that are not in any group synchronize with every replica.
+func (c *Cluster) Partition(groups ...[]int) error {
+	addrs := make([][]string, len(groups))
+	for i, group := range groups {
+		for _, idx := range group {
+			if idx < 0 || idx >= len(c.Servers) {
+				return fmt.Errorf("no replica %d in a cluster of %d replicas", idx, len(c.Servers))
+			}
+			addrs[i] = append(addrs[i], c.Addrs[idx])
+		}
+	}
+
+	c.Network.Partition(addrs...)
+	return nil
+}
+
+// Heal the partition of the replicas.
+func (c *Cluster) Heal() {
+	c.Network.Heal()
+}
+
+// Put the value of the key on the replica, returning the version created.
+func (c *Cluster) Put(idx int, key string, value []byte) (string, error) {
+	client, err := c.client(idx)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reply, err := client.PutValue(ctx, &pb.PutRequest{Key: key, Value: value})
+	if err != nil {
+		return "", err
+	}
+
+	if !reply.Success {
+		return "", errors.New(reply.Error)
+	}
+	return reply.Version, nil
+}
+
+// Get the value and version of the key on the replica.
+func (c *Cluster) Get(idx int, key string) ([]byte, string, error) {
+	client, err := c.client(idx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reply, err := client.GetValue(ctx, &pb.GetRequest{Key: key})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !reply.Success {
+		return nil, "", errors.New(reply.Error)
+	}
+	return reply.Value, reply.Version, nil
+}
+
+// Views returns the latest version of every key on each replica.
+func (c *Cluster) Views() []map[string]honu.Version {
+	views := make([]map[string]honu.Version, len(c.Servers))
+	for idx, server := range c.Servers {
+		views[idx] = server.View()
+	}
+	return views
+}
+
+// Converged returns true if every replica holds the same version of every
+// key.
+func (c *Cluster) Converged() bool {
+	return Identical(c.Views())
+}
+
+// WaitForConvergence waits until every replica holds the same version of
+// every key, returning the views of the replicas. If the replicas have not
+// converged before the timeout, an error describes how they differ.
+func (c *Cluster) WaitForConvergence(timeout time.Duration) ([]map[string]honu.Version, error) {
+	return c.waitFor(timeout, Identical)
+}
+
+// WaitForVersion waits until every replica holds the version of the key, or
+// a later one, returning the views of the replicas.
+func (c *Cluster) WaitForVersion(key, version string, timeout time.Duration) ([]map[string]honu.Version, error) {
+	target, err := honu.ParseVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.waitFor(timeout, func(views []map[string]honu.Version) bool {
+		for _, view := range views {
+			if vers, ok := view[key]; !ok || vers.Lesser(&target) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// waitFor polls the views of the replicas until the condition holds.
+func (c *Cluster) waitFor(timeout time.Duration, condition func([]map[string]honu.Version) bool) ([]map[string]honu.Version, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		views := c.Views()
+		if condition(views) {
+			return views, nil
+		}
+
+		if time.Now().After(deadline) {
+			return views, fmt.Errorf("replicas did not converge after %s: %s", timeout, Diff(views))
+		}
+
+		time.Sleep(ConvergencePoll)
+	}
+}
+
+// conn returns the connection to the replica, dialing it if necessary.
+func (c *Cluster) conn(idx int) (*grpc.ClientConn, error) {
+	if idx < 0 || idx >= len(c.Servers) {
+		return nil, fmt.Errorf("no replica %d in a cluster of %d replicas", idx, len(c.Servers))
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if conn, ok := c.conns[idx]; ok {
+		return conn, nil
+	}
+
+	conn, err := grpc.Dial(
+		c.Addrs[idx], grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithTimeout(timeout), grpc.WithDialer(c.Network.Dial),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to replica %d: %s", idx, err)
+	}
+
+	c.conns[idx] = conn
+	return conn, nil
+}
+
+// client returns a storage client of the replica.
+func (c *Cluster) client(idx int) (pb.StorageClient, error) {
+	conn, err := c.conn(idx)
+	if err != nil {
+		return nil, err
+	}
+	return pb.NewStorageClient(conn), nil
+}
+
+//===========================================================================
+// Comparing Views
+//===========================================================================
+
+// Identical returns true if every view holds the same version of every key.
+func Identical(views []map[string]honu.Version) bool {
+	for _, view := range views[1:] {
+		if len(view) != len(views[0]) {
+			return false
+		}
+
+		for key, vers := range views[0] {
+			if other, ok := view[key]; !ok || !other.Equals(&vers) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Diff describes the keys whose versions differ between the views, with the
+// version of the key held by each replica.
+func Diff(views []map[string]honu.Version) string {
+	keys := make(map[string]bool)
+	for _, view := range views {
+		for key := range view {
+			keys[key] = true
+		}
+	}
+
+	var diffs []string
+	for key := range keys {
+		versions := make([]string, len(views))
+		differ := false
+
+		for idx, view := range views {
+			versions[idx] = "none"
+			if vers, ok := view[key]; ok {
+				versions[idx] = vers.String()
+			}
+
+			if versions[idx] != versions[0] {
+				differ = true
+			}
+		}
+
+		if differ {
+			diffs = append(diffs, fmt.Sprintf("%s [%s]", key, strings.Join(versions, " ")))
+		}
+	}
+
+	if len(diffs) == 0 {
+		return "views are identical"
+	}
+
+	sort.Strings(diffs)
+	return fmt.Sprintf("%d keys differ: %s", len(diffs), strings.Join(diffs, ", "))
+}
@@ -0,0 +1,112 @@
+package honutest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/honu/honutest"
+)
+
+// startCluster creates and starts the cluster, stopping it when the test ends.
+func startCluster(t *testing.T, conf honutest.Config) *honutest.Cluster {
+	t.Helper()
+
+	cluster, err := honutest.NewCluster(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cluster.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := cluster.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+	return cluster
+}
+
+// put writes the value of the key on the replica, failing the test on error.
+func put(t *testing.T, c *honutest.Cluster, idx int, key, value string) string {
+	t.Helper()
+
+	version, err := c.Put(idx, key, []byte(value))
+	if err != nil {
+		t.Fatalf("could not put %s on replica %d: %s", key, idx, err)
+	}
+	return version
+}
+
+func TestConvergence(t *testing.T) {
+	cluster := startCluster(t, honutest.Config{Replicas: 3})
+
+	for i := 0; i < 30; i++ {
+		put(t, cluster, i%3, fmt.Sprintf("key%d", i%10), fmt.Sprintf("value%d", i))
+	}
+
+	views := honutest.AssertConverged(t, cluster, 10*time.Second)
+	if len(views[0]) != 10 {
+		t.Fatalf("expected 10 keys on every replica, found %d", len(views[0]))
+	}
+
+	// Every replica reads the same value as the replica it converged with
+	for key := range views[0] {
+		expected, version, err := cluster.Get(0, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for idx := 1; idx < 3; idx++ {
+			value, vers, err := cluster.Get(idx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(value) != string(expected) || vers != version {
+				t.Errorf("replica %d has %s=%q (%s), replica 0 has %q (%s)", idx, key, value, vers, expected, version)
+			}
+		}
+	}
+}
+
+func TestPartitionHeal(t *testing.T) {
+	cluster := startCluster(t, honutest.Config{Replicas: 3})
+	if err := cluster.Partition([]int{0}, []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	east := put(t, cluster, 0, "east", "written in the east")
+	west := put(t, cluster, 1, "west", "written in the west")
+
+	// Writes reach the replicas of their own side of the partition only
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, ok := cluster.Servers[2].View()["west"]; ok {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("write in the west did not reach replica 2 during the partition")
+		}
+		time.Sleep(honutest.ConvergencePoll)
+	}
+
+	if _, ok := cluster.Servers[0].View()["west"]; ok {
+		t.Fatal("write in the west reached replica 0 during the partition")
+	}
+
+	for idx := 1; idx < 3; idx++ {
+		if _, ok := cluster.Servers[idx].View()["east"]; ok {
+			t.Fatalf("write in the east reached replica %d during the partition", idx)
+		}
+	}
+
+	// Once the partition heals, the replicas converge on both writes
+	cluster.Heal()
+	honutest.AssertVisible(t, cluster, "east", east, 20*time.Second)
+	honutest.AssertVisible(t, cluster, "west", west, 20*time.Second)
+	honutest.AssertConverged(t, cluster, 20*time.Second)
+}
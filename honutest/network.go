@@ -0,0 +1,235 @@
+// Package honutest runs clusters of Honu replicas in a single process for
+// integration tests. Replicas listen and dial each other on an in-memory
+// network rather than TCP, so many clusters can run side by side without
+// allocating ports, and tests can inject writes and wait for anti-entropy to
+// converge the replicas.
+package honutest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bbengfort/honu"
+)
+
+// ErrListenerClosed is returned when accepting connections on a listener of
+// the in-memory network that has been closed.
+var ErrListenerClosed = errors.New("in-memory listener is closed")
+
+//===========================================================================
+// In-Memory Network
+//===========================================================================
+
+// NewNetwork creates an in-memory network with no listeners.
+func NewNetwork() *Network {
+	return &Network{
+		listeners: make(map[string]*listener),
+		conns:     make(map[*conn]bool),
+	}
+}
+
+// Network connects the replicas of a cluster in memory. Each connection is a
+// synchronous, full duplex pipe between the dialer and the listener of the
+// address, which can be any string that is unique on the network. The
+// network can be partitioned so that addresses in different groups cannot
+// connect to each other until it is healed.
+type Network struct {
+	sync.Mutex
+	listeners map[string]*listener // Listeners by the address they listen on
+	groups    map[string]int       // The partition group of each address
+	conns     map[*conn]bool       // Connections dialed from an address
+}
+
+// Listen for connections to the address on the network.
+func (n *Network) Listen(addr string) (net.Listener, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if _, ok := n.listeners[addr]; ok {
+		return nil, fmt.Errorf("address %s is already in use", addr)
+	}
+
+	lis := &listener{
+		addr:    memoryAddr(addr),
+		network: n,
+		conns:   make(chan net.Conn),
+		closed:  make(chan struct{}),
+	}
+
+	n.listeners[addr] = lis
+	return lis, nil
+}
+
+// Dial connects to the listener of the address, waiting up to the timeout for
+// the connection to be accepted. A timeout of zero waits indefinitely. It has
+// the signature of a honu.Dialer; the connections are never partitioned.
+func (n *Network) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	return n.DialFrom("", addr, timeout)
+}
+
+// Dialer returns the dialer of the replica listening on the address, whose
+// connections to peers are partitioned with it.
+func (n *Network) Dialer(from string) honu.Dialer {
+	return func(addr string, timeout time.Duration) (net.Conn, error) {
+		return n.DialFrom(from, addr, timeout)
+	}
+}
+
+// DialFrom connects the address to the listener of another address, failing
+// if they are partitioned. Connections from the empty address are not
+// partitioned.
+func (n *Network) DialFrom(from, addr string, timeout time.Duration) (net.Conn, error) {
+	n.Lock()
+	lis, ok := n.listeners[addr]
+	partitioned := n.partitioned(from, addr)
+	n.Unlock()
+
+	if partitioned {
+		return nil, fmt.Errorf("could not connect to %s: network is partitioned", addr)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("could not connect to %s: connection refused", addr)
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
+	local, remote := net.Pipe()
+	select {
+	case lis.conns <- remote:
+		if from == "" {
+			return local, nil
+		}
+
+		c := &conn{Conn: local, from: from, to: addr, network: n}
+		n.Lock()
+		n.conns[c] = true
+		n.Unlock()
+		return c, nil
+	case <-lis.closed:
+		return nil, fmt.Errorf("could not connect to %s: connection refused", addr)
+	case <-expired:
+		return nil, fmt.Errorf("could not connect to %s: timeout", addr)
+	}
+}
+
+// Partition the network into groups of addresses that can only connect to
+// addresses in their own group, closing the connections between groups.
+// Addresses that are not in any group can connect to every address. A
+// partition replaces the previous one.
+func (n *Network) Partition(groups ...[]string) {
+	n.Lock()
+	n.groups = make(map[string]int)
+	for idx, group := range groups {
+		for _, addr := range group {
+			n.groups[addr] = idx
+		}
+	}
+
+	var severed []*conn
+	for c := range n.conns {
+		if n.partitioned(c.from, c.to) {
+			severed = append(severed, c)
+		}
+	}
+	n.Unlock()
+
+	for _, c := range severed {
+		c.Close()
+	}
+}
+
+// Heal the partition so that every address can connect to each other again.
+func (n *Network) Heal() {
+	n.Lock()
+	n.groups = nil
+	n.Unlock()
+}
+
+// partitioned returns true if the addresses are in different groups of the
+// partition. Must be called under lock.
+func (n *Network) partitioned(from, to string) bool {
+	if from == "" {
+		return false
+	}
+
+	group, ok := n.groups[from]
+	other, isOther := n.groups[to]
+	return ok && isOther && group != other
+}
+
+//===========================================================================
+// In-Memory Connections
+//===========================================================================
+
+// conn is a connection dialed from an address, which is closed if the
+// addresses are partitioned.
+type conn struct {
+	net.Conn
+	from    string
+	to      string
+	network *Network
+}
+
+// Close the connection and stop tracking it on the network.
+func (c *conn) Close() error {
+	c.network.Lock()
+	delete(c.network.conns, c)
+	c.network.Unlock()
+	return c.Conn.Close()
+}
+
+//===========================================================================
+// In-Memory Listener
+//===========================================================================
+
+// listener accepts the connections dialed to its address on the network.
+type listener struct {
+	addr    memoryAddr
+	network *Network
+	conns   chan net.Conn
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// Accept waits for and returns the next connection to the listener.
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, ErrListenerClosed
+	}
+}
+
+// Close the listener and remove it from the network, so that its address
+// can be listened on again.
+func (l *listener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+
+		l.network.Lock()
+		if l.network.listeners[string(l.addr)] == l {
+			delete(l.network.listeners, string(l.addr))
+		}
+		l.network.Unlock()
+	})
+	return nil
+}
+
+// Addr returns the address the listener is listening on.
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
+
+// memoryAddr is the address of a listener on an in-memory network.
+type memoryAddr string
+
+func (a memoryAddr) Network() string { return "memory" }
+func (a memoryAddr) String() string  { return string(a) }
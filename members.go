@@ -0,0 +1,596 @@
+package honu
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+
+	pb "github.com/bbengfort/honu/rpc"
+)
+
+//===========================================================================
+// Cluster Membership
+//===========================================================================
+
+// MemberStatus describes the liveness of a replica in the cluster.
+type MemberStatus uint32
+
+// Statuses of replicas in the cluster. A member that has left is kept as a
+// tombstone so that stale gossip cannot resurrect it. Suspect and dead members
+// are assigned by the failure detector and can be refuted by the member.
+const (
+	MemberAlive MemberStatus = iota
+	MemberLeft
+	MemberSuspect
+	MemberDead
+)
+
+var memberStatusStrings = [...]string{"alive", "left", "suspect", "dead"}
+
+// Order in which statuses supersede each other at the same incarnation.
+var memberStatusPrecedence = [...]int{0, 3, 1, 2}
+
+// String returns a human readable representation of the status.
+func (s MemberStatus) String() string {
+	if int(s) < len(memberStatusStrings) {
+		return memberStatusStrings[s]
+	}
+	return fmt.Sprintf("unknown status %d", s)
+}
+
+// Supersedes returns true if the status overrides the other status when both
+// are reported for the same incarnation of a member.
+func (s MemberStatus) Supersedes(o MemberStatus) bool {
+	if int(s) >= len(memberStatusPrecedence) || int(o) >= len(memberStatusPrecedence) {
+		return false
+	}
+	return memberStatusPrecedence[s] > memberStatusPrecedence[o]
+}
+
+// Member is the local record of a replica in the cluster.
+type Member struct {
+	Addr        string       // The dialable address of the replica
+	PID         uint64       // The process id of the replica (zero if unknown)
+	Status      MemberStatus // The liveness status of the replica
+	Incarnation uint64       // Orders updates, only incremented by the member itself
+	Region      string       // Optional label of the location of the replica
+	Updated     time.Time    // The last time the member was modified locally
+	Verified    bool         // If the identity of the member was established by handshake
+}
+
+// NewMembership creates the membership view for the local replica. The
+// incarnation is initialized from the clock so that a replica that rejoins
+// after a restart supersedes the tombstone it left behind.
+func NewMembership(pid uint64) *Membership {
+	return &Membership{
+		local: &Member{
+			PID:         pid,
+			Status:      MemberAlive,
+			Incarnation: uint64(time.Now().UnixNano()),
+			Updated:     time.Now(),
+		},
+		members: make(map[string]*Member),
+		ignored: make(map[string]bool),
+	}
+}
+
+// Membership maintains the local view of the replicas in the cluster. The
+// view is updated by join and leave requests as well as by merging the
+// membership lists that are piggybacked on anti-entropy sessions.
+type Membership struct {
+	sync.RWMutex
+	local   *Member            // The member record of the local replica
+	members map[string]*Member // Remote members keyed by address
+	ignored map[string]bool    // Aliases of the local replica or other members and foreign replicas
+}
+
+// Local returns the address the local replica advertises to its peers.
+func (m *Membership) Local() string {
+	m.RLock()
+	defer m.RUnlock()
+	return m.local.Addr
+}
+
+// Advertise sets the address of the local replica that is sent to peers.
+func (m *Membership) Advertise(addr string) {
+	m.Lock()
+	defer m.Unlock()
+	m.local.Addr = addr
+}
+
+// Locate sets the region label of the local replica that is sent to peers.
+func (m *Membership) Locate(region string) {
+	m.Lock()
+	defer m.Unlock()
+	m.local.Region = region
+}
+
+// Region returns the region label of the local replica.
+func (m *Membership) Region() string {
+	m.RLock()
+	defer m.RUnlock()
+	return m.local.Region
+}
+
+// Add a statically configured peer to the membership as alive. Because the
+// incarnation is zero, any update received by gossip will replace it. Returns
+// false if the member is already known or is the local replica.
+func (m *Membership) Add(addr string) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.members[addr]; ok || addr == m.local.Addr || m.ignored[addr] {
+		return false
+	}
+
+	m.members[addr] = &Member{Addr: addr, Status: MemberAlive, Updated: time.Now()}
+	return true
+}
+
+// Merge membership updates received from a remote peer into the local view.
+// An update is applied if it has a later incarnation, or if it has the same
+// incarnation and supersedes the current status. If a peer reports that the
+// local replica is suspect or dead, the suspicion is refuted by incrementing
+// the local incarnation. Returns the members whose status has changed.
+func (m *Membership) Merge(updates []*pb.Member) (changes []Member) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, update := range updates {
+		status := MemberStatus(update.Status)
+		if update.Addr == "" || m.ignored[update.Addr] {
+			continue
+		}
+
+		// Ignore other addresses of the local replica
+		if update.Addr != m.local.Addr && update.Pid > 0 && update.Pid == m.local.PID {
+			m.ignore(update.Addr)
+			continue
+		}
+
+		// Refute suspicions about the local replica
+		if update.Addr == m.local.Addr {
+			if m.local.Status == MemberAlive && update.Incarnation >= m.local.Incarnation && status.Supersedes(MemberAlive) {
+				m.local.Incarnation = update.Incarnation + 1
+				m.local.Updated = time.Now()
+				info("refuted %s status with incarnation %d", status, m.local.Incarnation)
+			}
+			continue
+		}
+
+		member, ok := m.members[update.Addr]
+		if !ok {
+			member = &Member{
+				Addr:        update.Addr,
+				PID:         update.Pid,
+				Status:      status,
+				Incarnation: update.Incarnation,
+				Region:      update.Region,
+				Updated:     time.Now(),
+			}
+
+			m.members[update.Addr] = member
+			if status != MemberLeft {
+				changes = append(changes, *member)
+			}
+
+			// Statically configured aliases of the member are superseded by
+			// the address that the member advertises.
+			for _, alias := range m.aliases(member) {
+				changes = append(changes, Member{Addr: alias, Status: MemberLeft})
+			}
+			continue
+		}
+
+		if update.Incarnation < member.Incarnation {
+			continue
+		}
+
+		if update.Incarnation == member.Incarnation && !status.Supersedes(member.Status) {
+			continue
+		}
+
+		if update.Pid > 0 && !member.Verified {
+			member.PID = update.Pid
+			for _, alias := range m.aliases(member) {
+				changes = append(changes, Member{Addr: alias, Status: MemberLeft})
+			}
+		}
+
+		member.Incarnation = update.Incarnation
+		if update.Region != "" {
+			member.Region = update.Region
+		}
+
+		if member.Status != status {
+			member.Status = status
+			member.Updated = time.Now()
+			changes = append(changes, *member)
+		}
+	}
+
+	return changes
+}
+
+// Suspect marks an alive member as suspect at its current incarnation,
+// returning the change or nil if the member is not alive.
+func (m *Membership) Suspect(addr string) *Member {
+	return m.transition(addr, MemberAlive, MemberSuspect, 0)
+}
+
+// Confirm marks a suspect member as dead if it has not refuted the suspicion
+// at the given incarnation, returning the change or nil if it has.
+func (m *Membership) Confirm(addr string, incarnation uint64) *Member {
+	return m.transition(addr, MemberSuspect, MemberDead, incarnation)
+}
+
+// transition the member from one status to another, returning a copy of the
+// updated member if the transition occurred. If the incarnation is nonzero it
+// must match the incarnation of the member.
+func (m *Membership) transition(addr string, from, to MemberStatus, incarnation uint64) *Member {
+	m.Lock()
+	defer m.Unlock()
+
+	member, ok := m.members[addr]
+	if !ok || member.Status != from {
+		return nil
+	}
+
+	if incarnation > 0 && member.Incarnation != incarnation {
+		return nil
+	}
+
+	member.Status = to
+	member.Updated = time.Now()
+
+	change := *member
+	return &change
+}
+
+// Verify records the PID of the member at the address as established by a
+// handshake. If the PID belongs to the local replica or to another member
+// that advertised a different address, the address is an alias; it is removed
+// from the membership and ignored from then on. Returns false for aliases.
+func (m *Membership) Verify(addr string, pid uint64) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if pid == m.local.PID {
+		m.ignore(addr)
+		return false
+	}
+
+	for _, member := range m.members {
+		if member.Addr != addr && member.PID == pid && member.Incarnation > 0 {
+			m.ignore(addr)
+			return false
+		}
+	}
+
+	if member, ok := m.members[addr]; ok {
+		member.PID = pid
+		member.Verified = true
+	}
+	return true
+}
+
+// Ignore removes the member with the address from the membership and ignores
+// any further updates about it, e.g. because it belongs to another cluster.
+func (m *Membership) Ignore(addr string) {
+	m.Lock()
+	defer m.Unlock()
+	m.ignore(addr)
+}
+
+// ignore the address without locking the membership.
+func (m *Membership) ignore(addr string) {
+	delete(m.members, addr)
+	m.ignored[addr] = true
+}
+
+// aliases ignores any statically configured members that have the same PID as
+// the specified member but a different address, returning their addresses.
+func (m *Membership) aliases(member *Member) []string {
+	var aliases []string
+	if member.PID == 0 {
+		return aliases
+	}
+
+	for addr, other := range m.members {
+		if addr != member.Addr && other.PID == member.PID && other.Incarnation == 0 {
+			m.ignore(addr)
+			aliases = append(aliases, addr)
+		}
+	}
+	return aliases
+}
+
+// Get returns a copy of the member with the specified address.
+func (m *Membership) Get(addr string) (Member, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	member, ok := m.members[addr]
+	if !ok {
+		return Member{}, false
+	}
+	return *member, true
+}
+
+// Leave marks the local replica as having left the cluster and returns the
+// update that should be announced to the peers.
+func (m *Membership) Leave() *pb.Member {
+	m.Lock()
+	defer m.Unlock()
+
+	m.local.Status = MemberLeft
+	m.local.Updated = time.Now()
+	return m.local.topb()
+}
+
+// Alive returns the sorted addresses of all remote members that are alive.
+func (m *Membership) Alive() []string {
+	return m.Filter(MemberAlive)
+}
+
+// Filter returns the sorted addresses of all remote members that have any of
+// the specified statuses.
+func (m *Membership) Filter(statuses ...MemberStatus) []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	addrs := make([]string, 0, len(m.members))
+	for addr, member := range m.members {
+		for _, status := range statuses {
+			if member.Status == status {
+				addrs = append(addrs, addr)
+				break
+			}
+		}
+	}
+
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Serialize the membership to save to JSON format.
+func (m *Membership) Serialize() map[string]interface{} {
+	m.RLock()
+	defer m.RUnlock()
+
+	data := make(map[string]interface{})
+	for addr, member := range m.members {
+		data[addr] = member.Serialize()
+	}
+	return data
+}
+
+// Serialize the Member to write to disk
+func (m *Member) Serialize() map[string]interface{} {
+	data := make(map[string]interface{})
+	data["PID"] = m.PID
+	data["Status"] = m.Status.String()
+	data["Incarnation"] = m.Incarnation
+	data["Region"] = m.Region
+	data["Updated"] = m.Updated
+	data["Verified"] = m.Verified
+	return data
+}
+
+// topb returns the membership list, including the local replica, to be
+// piggybacked on gossip messages.
+func (m *Membership) topb() []*pb.Member {
+	m.RLock()
+	defer m.RUnlock()
+
+	members := make([]*pb.Member, 0, len(m.members)+1)
+	members = append(members, m.local.topb())
+	for _, member := range m.members {
+		members = append(members, member.topb())
+	}
+	return members
+}
+
+func (m *Member) topb() *pb.Member {
+	return &pb.Member{
+		Addr:        m.Addr,
+		Pid:         m.PID,
+		Status:      uint32(m.Status),
+		Incarnation: m.Incarnation,
+		Region:      m.Region,
+	}
+}
+
+// advertise returns a dialable address for the local replica, filling in the
+// hostname if the server is listening on all interfaces (e.g. ":3264").
+func advertise(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+
+	if host, err = os.Hostname(); err != nil {
+		warne(err)
+		return addr
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+//===========================================================================
+// Server Membership Methods
+//===========================================================================
+
+// Seeds sets the addresses of peers that the server will join the cluster
+// through when it is run. Only one of the seeds must be reachable.
+func (s *Server) Seeds(seeds []string) {
+	s.seeds = seeds
+}
+
+// joinCluster sends a join request to each seed in turn until one of them
+// replies with its view of the cluster membership, which is then merged.
+func (s *Server) joinCluster() error {
+	req := &pb.JoinRequest{Member: s.members.local.topb()}
+
+	for _, seed := range s.seeds {
+		conn, err := s.dial(context.Background(), seed)
+		if err != nil {
+			warn("could not connect to seed %s: %s", seed, err)
+			continue
+		}
+
+		// Do not join through the local replica or a seed in another cluster
+		if err = s.handshake(conn, seed); err != nil {
+			conn.Close()
+			if err != ErrSelfConnection && err != ErrAliasConnection {
+				warne(err)
+			}
+			continue
+		}
+
+		rep, err := pb.NewGossipClient(conn).Join(context.Background(), req)
+		conn.Close()
+
+		if err != nil {
+			warn("could not join through seed %s: %s", seed, err)
+			continue
+		}
+
+		if !rep.Success {
+			warn("seed %s refused join: %s", seed, rep.Error)
+			continue
+		}
+
+		s.membershipChanged(s.members.Merge(rep.Members))
+		info("joined cluster through %s with %d peers", seed, len(s.members.Alive()))
+		return nil
+	}
+
+	return fmt.Errorf("could not join the cluster through any of %d seeds", len(s.seeds))
+}
+
+// leaveCluster announces the graceful departure of the local replica to all
+// alive members, waiting for acknowledgement or timeout from each.
+func (s *Server) leaveCluster() error {
+	req := &pb.LeaveRequest{Member: s.members.Leave()}
+	group := new(errgroup.Group)
+
+	for _, peer := range s.members.Alive() {
+		addr := peer
+		group.Go(func() error {
+			conn, err := s.dial(context.Background(), addr)
+			if err != nil {
+				return fmt.Errorf("could not announce leave to %s: %s", addr, err)
+			}
+			defer conn.Close()
+
+			if _, err = pb.NewGossipClient(conn).Leave(context.Background(), req); err != nil {
+				return fmt.Errorf("could not announce leave to %s: %s", addr, err)
+			}
+			return nil
+		})
+	}
+
+	return group.Wait()
+}
+
+// membershipChanged adds bandit arms and sync stats for peers that join the
+// cluster and removes the arms of peers that have left. Dead peers are kept
+// but excluded from selection until they are alive again.
+func (s *Server) membershipChanged(changes []Member) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, member := range changes {
+		peer := member.Addr
+		arm := s.arm(peer)
+
+		if member.Status == MemberLeft {
+			if arm >= 0 {
+				s.peers = append(s.peers[:arm], s.peers[arm+1:]...)
+				s.bandit.Remove(arm)
+				info("peer %s has left the cluster", peer)
+			}
+
+			if s.handoff != nil {
+				go s.handoff.Forget(peer)
+			}
+
+			if s.repair != nil {
+				go s.repair.Forget(peer)
+			}
+			continue
+		}
+
+		if arm < 0 {
+			s.peers = append(s.peers, peer)
+			arm = s.bandit.Add()
+
+			if _, ok := s.syncs[peer]; !ok {
+				s.syncs[peer] = newSyncStats()
+			}
+
+			s.restore(arm, member)
+
+			info("peer %s has joined the cluster", peer)
+		}
+
+		if member.Status == MemberDead {
+			s.bandit.Disable(arm)
+			status("peer %s is dead", peer)
+		} else {
+			s.bandit.Enable(arm)
+			debug("peer %s is %s", peer, member.Status)
+
+			// Replay the writes the peer missed while it was unreachable
+			if s.handoff != nil && member.Status == MemberAlive {
+				go s.handoff.Replay(peer)
+			}
+		}
+	}
+}
+
+// arm returns the index of the bandit arm for the peer or -1 if the peer is
+// not being replicated to. The caller must hold the server lock.
+func (s *Server) arm(peer string) int {
+	for i, p := range s.peers {
+		if p == peer {
+			return i
+		}
+	}
+	return -1
+}
+
+//===========================================================================
+// Server Membership RPC methods
+//===========================================================================
+
+// Join handles a request from a new replica to join the cluster, adding it
+// to the membership and replying with the current view of the cluster so
+// that the new replica can start anti-entropy with its peers.
+func (s *Server) Join(ctx context.Context, in *pb.JoinRequest) (*pb.JoinReply, error) {
+	if s.bandit == nil {
+		return &pb.JoinReply{Success: false, Error: "server is not replicating"}, nil
+	}
+
+	s.membershipChanged(s.members.Merge([]*pb.Member{in.Member}))
+	return &pb.JoinReply{Success: true, Members: s.members.topb()}, nil
+}
+
+// Leave handles the announcement that a replica is leaving the cluster,
+// removing it from peer selection. The departure is disseminated to the rest
+// of the cluster by gossip.
+func (s *Server) Leave(ctx context.Context, in *pb.LeaveRequest) (*pb.LeaveReply, error) {
+	if s.bandit == nil {
+		return &pb.LeaveReply{Success: false}, nil
+	}
+
+	s.membershipChanged(s.members.Merge([]*pb.Member{in.Member}))
+	return &pb.LeaveReply{Success: true}, nil
+}
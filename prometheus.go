@@ -0,0 +1,285 @@
+package honu
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// LatencyBuckets are the upper bounds in seconds of the buckets of the RPC
+// latency histograms.
+var LatencyBuckets = []float64{
+	0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+//===========================================================================
+// Live Metrics
+//===========================================================================
+
+// ServeMetrics exposes live metrics of the server in the Prometheus text
+// format at /metrics on the specified address, and counts the requests and
+// latency of every RPC that is served. Must be called before Run.
+func (s *Server) ServeMetrics(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not listen for metrics on %s: %s", addr, err)
+	}
+
+	s.rpcs = &RPCMetrics{methods: make(map[string]*rpcStats)}
+	s.exporter = lis
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.exposeMetrics)
+	go func() {
+		if err := http.Serve(lis, mux); err != nil && !s.isStopped() {
+			warne(err)
+		}
+	}()
+
+	info("serving metrics on %s/metrics", addr)
+	return nil
+}
+
+// exposeMetrics writes the current metrics of the server in the Prometheus
+// text exposition format.
+func (s *Server) exposeMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	out := &exposition{w: bufio.NewWriter(w)}
+
+	s.rpcs.expose(out)
+
+	s.Lock()
+	var throughput float64
+	accesses := s.reads + s.writes
+	if duration := s.finished.Sub(s.started); accesses > 0 && duration > 0 {
+		throughput = float64(accesses) / duration.Seconds()
+	}
+
+	out.family("honu_reads_total", "counter", "Number of reads served.")
+	out.sample("honu_reads_total", float64(s.reads))
+	out.family("honu_writes_total", "counter", "Number of writes served.")
+	out.sample("honu_writes_total", float64(s.writes))
+	out.family("honu_throughput", "gauge", "Accesses per second between the first and last access.")
+	out.sample("honu_throughput", throughput)
+
+	out.family("honu_access_duration_seconds", "summary", "Time taken to serve reads and writes.")
+	for _, method := range []string{"read", "write"} {
+		hist := s.latency[method]
+		for _, pct := range Percentiles {
+			quantile := strconv.FormatFloat(pct.Rank, 'g', -1, 64)
+			out.sample("honu_access_duration_seconds", hist.Percentile(pct.Rank).Seconds(), "method", method, "quantile", quantile)
+		}
+		out.sample("honu_access_duration_seconds_sum", hist.Total().Seconds(), "method", method)
+		out.sample("honu_access_duration_seconds_count", float64(hist.N()), "method", method)
+	}
+
+	// Anti-entropy synchronizations with each peer
+	peers := make([]string, 0, len(s.syncs))
+	for peer := range s.syncs {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(*SyncStats) uint64
+	}{
+		{"honu_sync_sessions_total", "Anti-entropy sessions with the peer.", func(m *SyncStats) uint64 { return m.Syncs }},
+		{"honu_sync_pulls_total", "Successful pulls from the peer.", func(m *SyncStats) uint64 { return m.Pulls }},
+		{"honu_sync_pushes_total", "Successful pushes to the peer.", func(m *SyncStats) uint64 { return m.Pushes }},
+		{"honu_sync_misses_total", "Unsuccessful anti-entropy sessions with the peer.", func(m *SyncStats) uint64 { return m.Misses }},
+		{"honu_sync_versions_total", "Versions exchanged with the peer.", func(m *SyncStats) uint64 { return m.Versions }},
+		{"honu_sync_throttled_total", "Exchanges with the peer deferred by the bandwidth budget.", func(m *SyncStats) uint64 { return m.Throttled }},
+		{"honu_sync_sent_bytes_total", "Bytes sent to the peer by anti-entropy.", func(m *SyncStats) uint64 { return m.Sent }},
+		{"honu_sync_received_bytes_total", "Bytes received from the peer by anti-entropy.", func(m *SyncStats) uint64 { return m.Received }},
+	}
+
+	for _, counter := range counters {
+		out.family(counter.name, "counter", counter.help)
+		for _, peer := range peers {
+			out.sample(counter.name, float64(counter.value(s.syncs[peer])), "peer", peer)
+		}
+	}
+
+	// The learned value and selections of the bandit arm of each peer
+	if s.bandit != nil {
+		counts := s.bandit.Counts()
+		values := s.bandit.Values()
+
+		out.family("honu_bandit_arm_value", "gauge", "Estimated reward of the peer.")
+		for arm, peer := range s.peers {
+			out.sample("honu_bandit_arm_value", values[arm], "peer", peer)
+		}
+
+		out.family("honu_bandit_arm_selections", "gauge", "Times the peer was selected for anti-entropy.")
+		for arm, peer := range s.peers {
+			out.sample("honu_bandit_arm_selections", float64(counts[arm]), "peer", peer)
+		}
+	}
+	s.Unlock()
+
+	out.family("honu_store_keys", "gauge", "Number of keys in the store.")
+	out.sample("honu_store_keys", float64(s.nkeys()))
+	out.family("honu_history_versions", "gauge", "Number of versions in the version history.")
+	out.sample("honu_history_versions", float64(s.store.Versions()))
+
+	if err := out.flush(); err != nil {
+		warne(err)
+	}
+}
+
+//===========================================================================
+// RPC Metrics
+//===========================================================================
+
+// RPCMetrics counts the requests to every RPC method served by status code
+// and records their latency in a histogram.
+type RPCMetrics struct {
+	sync.Mutex
+	methods map[string]*rpcStats
+}
+
+// rpcStats are the request counts and latency histogram of a method.
+type rpcStats struct {
+	codes   map[string]uint64 // Number of requests by status code
+	buckets []uint64          // Number of requests in each latency bucket
+	sum     float64           // Total latency of all requests in seconds
+	count   uint64            // Number of requests
+}
+
+// Observe the status code and latency of a request to the method.
+func (m *RPCMetrics) Observe(method string, err error, latency time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+
+	method = strings.TrimPrefix(method, "/")
+	stats, ok := m.methods[method]
+	if !ok {
+		stats = &rpcStats{
+			codes:   make(map[string]uint64),
+			buckets: make([]uint64, len(LatencyBuckets)),
+		}
+		m.methods[method] = stats
+	}
+
+	seconds := latency.Seconds()
+	stats.codes[grpc.Code(err).String()]++
+	stats.sum += seconds
+	stats.count++
+
+	// Buckets are not cumulative until they are exposed
+	idx := sort.SearchFloat64s(LatencyBuckets, seconds)
+	if idx < len(LatencyBuckets) {
+		stats.buckets[idx]++
+	}
+}
+
+// UnaryInterceptor observes the unary requests handled by the server.
+func (m *RPCMetrics) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	rep, err := handler(ctx, req)
+	m.Observe(info.FullMethod, err, time.Since(start))
+	return rep, err
+}
+
+// StreamInterceptor observes the streams handled by the server.
+func (m *RPCMetrics) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	m.Observe(info.FullMethod, err, time.Since(start))
+	return err
+}
+
+// expose the request counts and latency histograms of every method.
+func (m *RPCMetrics) expose(out *exposition) {
+	m.Lock()
+	defer m.Unlock()
+
+	methods := make([]string, 0, len(m.methods))
+	for method := range m.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	out.family("honu_rpc_requests_total", "counter", "Number of RPC requests served by status code.")
+	for _, method := range methods {
+		codes := make([]string, 0, len(m.methods[method].codes))
+		for code := range m.methods[method].codes {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+
+		for _, code := range codes {
+			out.sample("honu_rpc_requests_total", float64(m.methods[method].codes[code]), "method", method, "code", code)
+		}
+	}
+
+	out.family("honu_rpc_duration_seconds", "histogram", "Latency of RPC requests served.")
+	for _, method := range methods {
+		stats := m.methods[method]
+
+		var cumulative uint64
+		for idx, bound := range LatencyBuckets {
+			cumulative += stats.buckets[idx]
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			out.sample("honu_rpc_duration_seconds_bucket", float64(cumulative), "method", method, "le", le)
+		}
+
+		out.sample("honu_rpc_duration_seconds_bucket", float64(stats.count), "method", method, "le", "+Inf")
+		out.sample("honu_rpc_duration_seconds_sum", stats.sum, "method", method)
+		out.sample("honu_rpc_duration_seconds_count", float64(stats.count), "method", method)
+	}
+}
+
+//===========================================================================
+// Prometheus Text Exposition Format
+//===========================================================================
+
+// exposition writes metric families in the Prometheus text format, keeping
+// the first error that occurs.
+type exposition struct {
+	w   *bufio.Writer
+	err error
+}
+
+// family writes the help and type of a metric family.
+func (e *exposition) family(name, kind, help string) {
+	e.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes the value of a metric with the label names and values.
+func (e *exposition) sample(name string, value float64, labels ...string) {
+	if len(labels) > 0 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+		}
+		name = fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+	}
+
+	e.printf("%s %s\n", name, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// flush the buffered metrics, returning the first error that occurred.
+func (e *exposition) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+func (e *exposition) printf(format string, a ...interface{}) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, a...)
+	}
+}
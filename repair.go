@@ -0,0 +1,276 @@
+package honu
+
+import (
+	"math/rand"
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	pb "github.com/bbengfort/honu/rpc"
+)
+
+//===========================================================================
+// Read Repair
+//===========================================================================
+
+// ReadRepair compares the version of every key that is read with the
+// versions of a random sample of peers in the background. If a peer has a
+// later version, it is installed locally; if the local version is later, it
+// is pushed to the stale peer. Hot keys therefore converge as they are read,
+// independently of anti-entropy. Only one repair of a key is in flight at a
+// time; reads of the key during the repair are not repaired again.
+type ReadRepair struct {
+	sync.Mutex
+	Fanout   int                         // The number of peers consulted on each read
+	Checks   uint64                      // The number of versions compared with peers
+	Repaired uint64                      // The number of later entries installed locally
+	Pushed   uint64                      // The number of later entries pushed to stale peers
+	Failures uint64                      // The number of peers that could not be consulted
+	server   *Server                     // The server whose reads are being repaired
+	inflight map[string]bool             // The keys that are being repaired
+	conns    map[string]*grpc.ClientConn // Connections to peers for repairs
+}
+
+// RepairReads configures the server to consult the specified number of peers
+// in the background whenever a key is read, repairing stale replicas.
+func (s *Server) RepairReads(fanout int) {
+	s.repair = &ReadRepair{
+		Fanout:   fanout,
+		server:   s,
+		inflight: make(map[string]bool),
+		conns:    make(map[string]*grpc.ClientConn),
+	}
+
+	info("repairing reads with %d peers", fanout)
+}
+
+// Read repairs the key with a random sample of the live peers, waiting
+// until every peer has been consulted.
+func (r *ReadRepair) Read(key string) {
+	if r.server.isStopped() {
+		return
+	}
+
+	r.Lock()
+	if r.inflight[key] {
+		r.Unlock()
+		return
+	}
+	r.inflight[key] = true
+	r.Unlock()
+
+	defer func() {
+		r.Lock()
+		delete(r.inflight, key)
+		r.Unlock()
+	}()
+
+	wg := new(sync.WaitGroup)
+	for _, peer := range r.sample() {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			r.repair(peer, key)
+		}(peer)
+	}
+	wg.Wait()
+}
+
+// Forget the connection to a peer that has left the cluster.
+func (r *ReadRepair) Forget(peer string) {
+	r.Lock()
+	defer r.Unlock()
+
+	if conn, ok := r.conns[peer]; ok {
+		conn.Close()
+		delete(r.conns, peer)
+	}
+}
+
+// Close the connections to the peers.
+func (r *ReadRepair) Close() {
+	r.Lock()
+	defer r.Unlock()
+
+	for peer, conn := range r.conns {
+		conn.Close()
+		delete(r.conns, peer)
+	}
+}
+
+// Serialize the read repair metrics to write to disk
+func (r *ReadRepair) Serialize() map[string]interface{} {
+	r.Lock()
+	defer r.Unlock()
+
+	data := make(map[string]interface{})
+	data["Fanout"] = r.Fanout
+	data["Checks"] = r.Checks
+	data["Repaired"] = r.Repaired
+	data["Pushed"] = r.Pushed
+	data["Failures"] = r.Failures
+	return data
+}
+
+// sample returns up to fanout random peers that are not dead.
+func (r *ReadRepair) sample() []string {
+	s := r.server
+	s.Lock()
+	defer s.Unlock()
+
+	peers := make([]string, 0, len(s.peers))
+	for _, peer := range s.peers {
+		if member, ok := s.members.Get(peer); ok && member.Status == MemberDead {
+			continue
+		}
+		peers = append(peers, peer)
+	}
+
+	for i := range peers {
+		j := i + rand.Intn(len(peers)-i)
+		peers[i], peers[j] = peers[j], peers[i]
+	}
+
+	if len(peers) > r.Fanout {
+		peers = peers[:r.Fanout]
+	}
+	return peers
+}
+
+// repair the key with the peer by sending the local version in a pull
+// request, which replies with the entry of the peer if it is later or
+// requests the local entry if it is later. Repairs are canceled when the
+// server shuts down.
+func (r *ReadRepair) repair(peer, key string) {
+	s := r.server
+	ctx := s.runContext()
+
+	conn, err := r.conn(ctx, peer)
+	if err != nil {
+		r.failed(peer, err)
+		return
+	}
+
+	// Get the local entry, the null version is earlier than any entry
+	var entry *Entry
+	version := NullVersion
+	s.store.RLock()
+	if local := s.store.GetEntry(key); local != nil {
+		entry = local.copy()
+		version = *entry.Version
+	}
+	s.store.RUnlock()
+
+	req := &pb.PullRequest{Versions: map[string]*pb.Version{key: version.topb()}}
+	if s.codec != nil {
+		req.Encoding = s.codec.Name()
+	}
+
+	ctx, span := s.tracer.Start(ctx, "read-repair")
+	span.SetAttribute("peer", peer)
+	span.SetAttribute("key", key)
+	defer span.Finish()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := pb.NewGossipClient(conn)
+	rep, err := client.Pull(ctx, req)
+	if err != nil {
+		r.drop(peer, conn)
+		r.failed(peer, err)
+		return
+	}
+
+	r.Lock()
+	r.Checks++
+	r.Unlock()
+
+	// The peer has a later version, install it locally
+	if pbentry, ok := rep.Entries[key]; ok {
+		if _, ok := s.accept(ctx, key, pbentry); ok {
+			r.Lock()
+			r.Repaired++
+			r.Unlock()
+			debug("repaired %s from %s", key, peer)
+		}
+		return
+	}
+
+	// The local version is later, push it to the stale peer
+	if _, ok := rep.Pull.Versions[key]; ok && entry != nil {
+		s.acknowledged(peer, rep.Pull.Encoding)
+		push := &pb.PushRequest{
+			Entries: map[string]*pb.Entry{key: encodeEntry(entry, s.pushCodec(peer))},
+		}
+
+		if _, err := client.Push(ctx, push); err != nil {
+			r.drop(peer, conn)
+			r.failed(peer, err)
+			return
+		}
+
+		r.Lock()
+		r.Pushed++
+		r.Unlock()
+		debug("repaired %s on %s", key, peer)
+	}
+}
+
+// conn returns the connection to the peer, dialing it if necessary.
+func (r *ReadRepair) conn(ctx context.Context, peer string) (*grpc.ClientConn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	conn, ok := r.conns[peer]
+	r.Unlock()
+
+	if ok {
+		return conn, nil
+	}
+
+	conn, err := r.server.dial(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not keep connections dialed while the server shut down
+	r.Lock()
+	defer r.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if prev, ok := r.conns[peer]; ok {
+		conn.Close()
+		return prev, nil
+	}
+
+	r.conns[peer] = conn
+	return conn, nil
+}
+
+// drop closes the connection to the peer after a failed request so that it
+// is redialed.
+func (r *ReadRepair) drop(peer string, conn *grpc.ClientConn) {
+	r.Lock()
+	if r.conns[peer] == conn {
+		delete(r.conns, peer)
+	}
+	r.Unlock()
+
+	conn.Close()
+}
+
+// failed records that the peer could not be consulted.
+func (r *ReadRepair) failed(peer string, err error) {
+	r.Lock()
+	r.Failures++
+	r.Unlock()
+	debug("could not repair reads with %s: %s", peer, err)
+}
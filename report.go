@@ -0,0 +1,250 @@
+package honu
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+//===========================================================================
+// Interval Reporter
+//===========================================================================
+
+// NewIntervalReporter creates a reporter that appends the metrics returned
+// by the collect function to the JSON Lines file at the path every interval.
+func NewIntervalReporter(path string, interval time.Duration, collect func(elapsed time.Duration) map[string]interface{}) (*IntervalReporter, error) {
+	out, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IntervalReporter{
+		interval: interval,
+		collect:  collect,
+		file:     out,
+	}, nil
+}
+
+// IntervalReporter writes a JSON Lines record of the metrics of every
+// interval, rather than a single record at the end of an experiment, so that
+// warm-up, steady state and degradation can be told apart in the analysis.
+// Each record holds the start, end and length of its interval in addition to
+// the metrics collected for it.
+type IntervalReporter struct {
+	sync.Mutex
+	interval time.Duration                                      // The time between records
+	collect  func(elapsed time.Duration) map[string]interface{} // Collects and resets the metrics of an interval
+	file     *os.File                                           // The JSON Lines file records are appended to
+	started  time.Time                                          // The start of the current interval
+	timer    *time.Timer                                        // Fires at the end of the current interval
+	stopped  bool                                               // If the reporter has been stopped
+	err      error                                              // The first error writing a record
+}
+
+// Start the first interval.
+func (r *IntervalReporter) Start() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.started = time.Now()
+	r.timer = time.AfterFunc(r.interval, r.tick)
+}
+
+// Stop the reporter, writing the record of the last partial interval and
+// closing the file. Returns the first error that occurred writing records.
+func (r *IntervalReporter) Stop() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.stopped {
+		return r.err
+	}
+
+	r.stopped = true
+	if r.timer != nil {
+		r.timer.Stop()
+		r.report(time.Now())
+	}
+
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// Error returns any issues the reporter had writing records
+func (r *IntervalReporter) Error() error {
+	r.Lock()
+	defer r.Unlock()
+	return r.err
+}
+
+// tick reports the interval that just ended and schedules the next one.
+func (r *IntervalReporter) tick() {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.stopped {
+		return
+	}
+
+	r.report(time.Now())
+	r.timer = time.AfterFunc(r.interval, r.tick)
+}
+
+// report writes the record of the interval that ended at the specified time
+// and starts the next one. Must be called under lock.
+func (r *IntervalReporter) report(finished time.Time) {
+	elapsed := finished.Sub(r.started)
+	record := r.collect(elapsed)
+	record["start"] = r.started
+	record["timestamp"] = finished
+	record["interval"] = elapsed.Seconds()
+	r.started = finished
+
+	if r.err != nil {
+		return
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		r.err = err
+		warne(err)
+		return
+	}
+
+	data = append(data, byte('\n'))
+	if _, err = r.file.Write(data); err != nil {
+		r.err = err
+		warne(err)
+	}
+}
+
+//===========================================================================
+// Interval Latencies
+//===========================================================================
+
+// Latencies collects the latencies observed during an interval in a
+// histogram so that they can be summarized with percentiles when the
+// interval is reported.
+type Latencies struct {
+	sync.Mutex
+	hist *Histogram
+}
+
+// Update the latencies with an observation.
+func (l *Latencies) Update(latency time.Duration) {
+	l.Lock()
+	if l.hist == nil {
+		l.hist = new(Histogram)
+	}
+	hist := l.hist
+	l.Unlock()
+
+	hist.Update(latency)
+}
+
+// Summary returns the number, mean, extrema and percentiles in seconds of
+// the latencies observed since the last summary, then resets them.
+func (l *Latencies) Summary() map[string]interface{} {
+	l.Lock()
+	hist := l.hist
+	l.hist = nil
+	l.Unlock()
+
+	data := make(map[string]interface{})
+	if hist == nil {
+		data["n"] = 0
+		return data
+	}
+
+	data["n"] = int(hist.N())
+	data["mean"] = hist.Mean().Seconds()
+	data["min"] = hist.Fastest().Seconds()
+	data["max"] = hist.Slowest().Seconds()
+	for _, pct := range Percentiles {
+		data[pct.Name] = hist.Percentile(pct.Rank).Seconds()
+	}
+	return data
+}
+
+//===========================================================================
+// Server Interval Metrics
+//===========================================================================
+
+// serverReport holds the latencies observed by the server during the current
+// interval and the totals of its counters at the end of the previous one.
+type serverReport struct {
+	reporter *IntervalReporter
+	reads    *Latencies        // The time taken to serve reads
+	writes   *Latencies        // The time taken to serve writes
+	syncs    *Latencies        // The latency of anti-entropy sessions
+	previous map[string]uint64 // The counters at the end of the previous interval
+}
+
+// ReportMetrics appends a record of the metrics of the server to the JSON
+// Lines file at the path every interval while it is running: the reads,
+// writes and throughput, the anti-entropy sessions, versions and bytes
+// exchanged during the interval, the number of keys and versions, and
+// summaries of the access and synchronization latencies. Must be called
+// before Run.
+func (s *Server) ReportMetrics(path string, interval time.Duration) error {
+	report := &serverReport{
+		reads:    new(Latencies),
+		writes:   new(Latencies),
+		syncs:    new(Latencies),
+		previous: make(map[string]uint64),
+	}
+
+	var err error
+	if report.reporter, err = NewIntervalReporter(path, interval, s.collectInterval); err != nil {
+		return err
+	}
+
+	s.report = report
+	info("reporting metrics every %s to %s", interval, path)
+	return nil
+}
+
+// collectInterval returns the metrics of the server during the interval.
+func (s *Server) collectInterval(elapsed time.Duration) map[string]interface{} {
+	// The sync counters are reported even if there are no peers to sync with
+	s.Lock()
+	totals := map[string]uint64{
+		"reads": s.reads, "writes": s.writes, "syncs": 0, "pulls": 0,
+		"pushes": 0, "misses": 0, "versions": 0, "sent": 0, "received": 0,
+	}
+	for _, metrics := range s.syncs {
+		totals["syncs"] += metrics.Syncs
+		totals["pulls"] += metrics.Pulls
+		totals["pushes"] += metrics.Pushes
+		totals["misses"] += metrics.Misses
+		totals["versions"] += metrics.Versions
+		totals["sent"] += metrics.Sent
+		totals["received"] += metrics.Received
+	}
+	s.Unlock()
+
+	data := make(map[string]interface{})
+	for name, total := range totals {
+		data[name] = total - s.report.previous[name]
+	}
+	s.report.previous = totals
+
+	var throughput float64
+	if elapsed > 0 {
+		accesses := data["reads"].(uint64) + data["writes"].(uint64)
+		throughput = float64(accesses) / elapsed.Seconds()
+	}
+
+	data["throughput"] = throughput
+	data["nkeys"] = s.nkeys()
+	data["nversions"] = s.store.Versions()
+	data["read_latency"] = s.report.reads.Summary()
+	data["write_latency"] = s.report.writes.Summary()
+	data["sync_latency"] = s.report.syncs.Summary()
+	data["host"] = s.addr
+	data["pid"] = s.members.local.PID
+	return data
+}
@@ -0,0 +1,341 @@
+package honu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RewardOptions are the parameters of the reward functions; each function
+// only uses the options that apply to it.
+type RewardOptions struct {
+	Latency   time.Duration      // Latency at which the latency reward is one half
+	Density   float64            // Versions per KB at which the density reward is one half
+	Staleness float64            // Versions of staleness reduced at which the staleness reward is one half
+	Weights   map[string]float64 // Weights of the named reward functions to combine
+}
+
+// NewRewardFunction creates the reward function with the specified name. The
+// weighted function combines the other named functions by opts.Weights.
+func NewRewardFunction(name string, opts RewardOptions) (RewardFunction, error) {
+	switch strings.ToLower(name) {
+	case "", "default":
+		return new(DefaultReward), nil
+	case "latency":
+		if opts.Latency <= 0 {
+			return nil, fmt.Errorf("latency reward scale must be positive, not %s", opts.Latency)
+		}
+		return &LatencyReward{Scale: opts.Latency}, nil
+	case "versions-per-byte":
+		if opts.Density <= 0 {
+			return nil, fmt.Errorf("versions per byte reward scale must be positive, not %f", opts.Density)
+		}
+		return &DensityReward{Scale: opts.Density}, nil
+	case "staleness":
+		if opts.Staleness <= 0 {
+			return nil, fmt.Errorf("staleness reward scale must be positive, not %f", opts.Staleness)
+		}
+		return &StalenessReward{Scale: opts.Staleness}, nil
+	case "weighted":
+		return NewWeightedReward(opts)
+	default:
+		return nil, fmt.Errorf("no anti-entropy reward function named %s", name)
+	}
+}
+
+// RewardFunction computes the reward of a peer from the outcome of an
+// anti-entropy session with it, which is used to update the bandit strategy.
+type RewardFunction interface {
+	Reward(sync *Synchronization) float64 // Compute the reward of the session
+	Serialize() map[string]interface{}    // Return a JSON representation of the function
+}
+
+// Synchronization describes the outcome of an anti-entropy session with a
+// peer. The zero value is an unsuccessful session (e.g. the peer could not
+// be reached), which all of the built-in reward functions reward with zero.
+type Synchronization struct {
+	Peer        string        // The peer that anti-entropy was conducted with
+	Pulled      bool          // If the pull request synchronized any versions
+	Pushed      bool          // If versions were pushed back to the peer
+	PullLatency time.Duration // The round trip latency of the pull request
+	PushLatency time.Duration // The round trip latency of the push request
+	PullItems   uint64        // The number of versions accepted from the peer
+	PushItems   uint64        // The number of versions sent to the peer
+	Bytes       uint64        // The size of the pull reply and push request
+	Staleness   uint64        // Sum of the scalar versions each replica was behind by
+}
+
+// Latency returns the total latency of the synchronization.
+func (s *Synchronization) Latency() time.Duration {
+	return s.PullLatency + s.PushLatency
+}
+
+// Items returns the total number of versions exchanged.
+func (s *Synchronization) Items() uint64 {
+	return s.PullItems + s.PushItems
+}
+
+// saturate maps a non-negative quantity to [0, 1), returning one half when
+// the quantity is equal to the scale.
+func saturate(x, scale float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return x / (x + scale)
+}
+
+//===========================================================================
+// Default Reward
+//===========================================================================
+
+// DefaultReward rewards successful pulls and pushes, low latency exchanges
+// and exchanges of multiple items. The reward is in [0, 1].
+type DefaultReward struct{}
+
+// Reward the synchronization with fixed bonuses.
+func (r *DefaultReward) Reward(sync *Synchronization) float64 {
+	if !sync.Pulled {
+		return 0
+	}
+
+	reward := 0.25 // add reward for a successful pull request
+	reward += r.latency(sync.PullLatency)
+
+	if sync.PullItems > 1 {
+		// add reward for multi-items
+		reward += 0.05
+	}
+
+	if sync.Pushed {
+		reward += 0.25 // add reward for a push request
+		reward += r.latency(sync.PushLatency)
+
+		if sync.PushItems > 1 {
+			// add reward for multi-items
+			reward += 0.05
+		}
+	}
+
+	return reward
+}
+
+// latency returns the reward for low latency requests.
+func (r *DefaultReward) latency(latency time.Duration) float64 {
+	if latency < 5*time.Millisecond {
+		return 0.20 // highest reward for local latencies
+	} else if latency <= 100*time.Millisecond {
+		return 0.10 // reward for close by links that don't globe span.
+	}
+	return 0
+}
+
+// Serialize the reward function to dump to JSON.
+func (r *DefaultReward) Serialize() map[string]interface{} {
+	data := make(map[string]interface{})
+	data["function"] = "default"
+	return data
+}
+
+//===========================================================================
+// Latency Reward
+//===========================================================================
+
+// LatencyReward rewards successful synchronizations by their latency alone,
+// decreasing from one toward zero as the latency grows: a synchronization
+// with the scale latency is rewarded with one half.
+type LatencyReward struct {
+	Scale time.Duration // The latency at which the reward is one half
+}
+
+// Reward the synchronization by its total latency.
+func (r *LatencyReward) Reward(sync *Synchronization) float64 {
+	if !sync.Pulled {
+		return 0
+	}
+	return 1 - saturate(float64(sync.Latency()), float64(r.Scale))
+}
+
+// Serialize the reward function to dump to JSON.
+func (r *LatencyReward) Serialize() map[string]interface{} {
+	data := make(map[string]interface{})
+	data["function"] = "latency"
+	data["scale"] = r.Scale.String()
+	return data
+}
+
+//===========================================================================
+// Versions per Byte Reward
+//===========================================================================
+
+// DensityReward rewards synchronizations by the number of versions exchanged
+// per KB of messages, so that peers that send many small, useful updates are
+// preferred over peers that send few large ones.
+type DensityReward struct {
+	Scale float64 // The versions per KB at which the reward is one half
+}
+
+// Reward the synchronization by the versions exchanged per KB.
+func (r *DensityReward) Reward(sync *Synchronization) float64 {
+	if !sync.Pulled || sync.Bytes == 0 {
+		return 0
+	}
+
+	density := float64(sync.Items()) / (float64(sync.Bytes) / 1024)
+	return saturate(density, r.Scale)
+}
+
+// Serialize the reward function to dump to JSON.
+func (r *DensityReward) Serialize() map[string]interface{} {
+	data := make(map[string]interface{})
+	data["function"] = "versions-per-byte"
+	data["scale"] = r.Scale
+	return data
+}
+
+//===========================================================================
+// Staleness Reduction Reward
+//===========================================================================
+
+// StalenessReward rewards synchronizations by how much they reduce the
+// staleness of the replicas, measured as the number of scalar versions each
+// replica was behind the other by for the keys that were exchanged.
+type StalenessReward struct {
+	Scale float64 // The staleness reduction at which the reward is one half
+}
+
+// Reward the synchronization by the staleness reduced.
+func (r *StalenessReward) Reward(sync *Synchronization) float64 {
+	if !sync.Pulled {
+		return 0
+	}
+	return saturate(float64(sync.Staleness), r.Scale)
+}
+
+// Serialize the reward function to dump to JSON.
+func (r *StalenessReward) Serialize() map[string]interface{} {
+	data := make(map[string]interface{})
+	data["function"] = "staleness"
+	data["scale"] = r.Scale
+	return data
+}
+
+//===========================================================================
+// Weighted Reward
+//===========================================================================
+
+// NewWeightedReward creates a weighted combination of the named reward
+// functions in opts.Weights, each of which is created with opts.
+func NewWeightedReward(opts RewardOptions) (*WeightedReward, error) {
+	if len(opts.Weights) == 0 {
+		return nil, fmt.Errorf("weighted reward requires at least one weight")
+	}
+
+	r := &WeightedReward{
+		Weights:   make(map[string]float64),
+		functions: make(map[string]RewardFunction),
+	}
+
+	for name, weight := range opts.Weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of %s reward must not be negative", name)
+		}
+
+		if strings.ToLower(name) == "weighted" {
+			return nil, fmt.Errorf("weighted reward cannot combine itself")
+		}
+
+		fn, err := NewRewardFunction(name, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Weights[name] = weight
+		r.functions[name] = fn
+		r.total += weight
+	}
+
+	if r.total == 0 {
+		return nil, fmt.Errorf("weighted reward requires a positive weight")
+	}
+
+	return r, nil
+}
+
+// WeightedReward combines reward functions with a weighted mean of their
+// rewards, so if all of the functions are in [0, 1] the reward is as well.
+type WeightedReward struct {
+	Weights   map[string]float64        // The weight of each named function
+	functions map[string]RewardFunction // The functions to combine
+	total     float64                   // The sum of the weights
+}
+
+// Reward the synchronization by the weighted mean of the functions.
+func (r *WeightedReward) Reward(sync *Synchronization) float64 {
+	reward := 0.0
+	for name, fn := range r.functions {
+		reward += r.Weights[name] * fn.Reward(sync)
+	}
+	return reward / r.total
+}
+
+// Serialize the reward function to dump to JSON.
+func (r *WeightedReward) Serialize() map[string]interface{} {
+	functions := make(map[string]interface{})
+	for name, fn := range r.functions {
+		params := fn.Serialize()
+		params["weight"] = r.Weights[name]
+		functions[name] = params
+	}
+
+	data := make(map[string]interface{})
+	data["function"] = "weighted"
+	data["functions"] = functions
+	return data
+}
+
+//===========================================================================
+// Reward weights configuration
+//===========================================================================
+
+// ParseRewardWeights parses weights of reward functions specified as a comma
+// separated list of name=weight pairs, e.g. "latency=0.5,staleness=0.5".
+func ParseRewardWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("could not parse reward weight %q", pair)
+		}
+
+		weight, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse reward weight %q: %s", pair, err)
+		}
+
+		weights[strings.TrimSpace(parts[0])] = weight
+	}
+	return weights, nil
+}
+
+// LoadRewardWeights loads the weights of reward functions from a JSON file
+// that contains an object mapping function names to weights.
+func LoadRewardWeights(path string) (map[string]float64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make(map[string]float64)
+	if err := json.Unmarshal(data, &weights); err != nil {
+		return nil, fmt.Errorf("could not parse reward weights in %s: %s", path, err)
+	}
+	return weights, nil
+}
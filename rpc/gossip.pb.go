@@ -1,25 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // source: gossip.proto
 
-/*
-Package rpc is a generated protocol buffer package.
-
-It is generated from these files:
-	gossip.proto
-	service.proto
-
-It has these top-level messages:
-	Version
-	Entry
-	PullRequest
-	PullReply
-	PushRequest
-	PushReply
-	GetRequest
-	GetReply
-	PutRequest
-	PutReply
-*/
 package rpc
 
 import proto "github.com/golang/protobuf/proto"
@@ -36,12 +17,6 @@ var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the proto package it is being compiled against.
-// A compilation error at this line likely means your copy of the
-// proto package needs to be updated.
-const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
-
 // Version represents the latest conflict-free version number for an object.
 type Version struct {
 	Scalar uint64 `protobuf:"varint,1,opt,name=scalar" json:"scalar,omitempty"`
@@ -51,7 +26,7 @@ type Version struct {
 func (m *Version) Reset()                    { *m = Version{} }
 func (m *Version) String() string            { return proto.CompactTextString(m) }
 func (*Version) ProtoMessage()               {}
-func (*Version) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (*Version) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
 
 func (m *Version) GetScalar() uint64 {
 	if m != nil {
@@ -67,18 +42,22 @@ func (m *Version) GetPid() uint64 {
 	return 0
 }
 
-// Entry represents a key/value entry that is being synchronized.
+// Entry represents a key/value entry that is being synchronized. The value
+// is compressed with the named encoding, if any. If the write is traced, the
+// traceparent of the span that last made it visible is propagated with it.
 type Entry struct {
 	Parent          *Version `protobuf:"bytes,1,opt,name=parent" json:"parent,omitempty"`
 	Version         *Version `protobuf:"bytes,2,opt,name=version" json:"version,omitempty"`
 	Value           []byte   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
 	TrackVisibility bool     `protobuf:"varint,4,opt,name=trackVisibility" json:"trackVisibility,omitempty"`
+	Encoding        string   `protobuf:"bytes,5,opt,name=encoding" json:"encoding,omitempty"`
+	Trace           string   `protobuf:"bytes,6,opt,name=trace" json:"trace,omitempty"`
 }
 
 func (m *Entry) Reset()                    { *m = Entry{} }
 func (m *Entry) String() string            { return proto.CompactTextString(m) }
 func (*Entry) ProtoMessage()               {}
-func (*Entry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*Entry) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
 
 func (m *Entry) GetParent() *Version {
 	if m != nil {
@@ -108,16 +87,35 @@ func (m *Entry) GetTrackVisibility() bool {
 	return false
 }
 
+func (m *Entry) GetEncoding() string {
+	if m != nil {
+		return m.Encoding
+	}
+	return ""
+}
+
+func (m *Entry) GetTrace() string {
+	if m != nil {
+		return m.Trace
+	}
+	return ""
+}
+
 // PullRequest sends a vector of versions to a remote and expects any more
-// recent versions of objects in reply.
+// recent versions of objects in reply. The remote should limit the size of
+// the entries in the reply to limit bytes (if not zero) and should compress
+// the values with the encoding.
 type PullRequest struct {
 	Versions map[string]*Version `protobuf:"bytes,1,rep,name=versions" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Members  []*Member           `protobuf:"bytes,2,rep,name=members" json:"members,omitempty"`
+	Limit    uint64              `protobuf:"varint,3,opt,name=limit" json:"limit,omitempty"`
+	Encoding string              `protobuf:"bytes,4,opt,name=encoding" json:"encoding,omitempty"`
 }
 
 func (m *PullRequest) Reset()                    { *m = PullRequest{} }
 func (m *PullRequest) String() string            { return proto.CompactTextString(m) }
 func (*PullRequest) ProtoMessage()               {}
-func (*PullRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*PullRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2} }
 
 func (m *PullRequest) GetVersions() map[string]*Version {
 	if m != nil {
@@ -126,19 +124,43 @@ func (m *PullRequest) GetVersions() map[string]*Version {
 	return nil
 }
 
+func (m *PullRequest) GetMembers() []*Member {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *PullRequest) GetLimit() uint64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *PullRequest) GetEncoding() string {
+	if m != nil {
+		return m.Encoding
+	}
+	return ""
+}
+
 // PullReply contains the entries for objects that have a later version. It
 // may also contain an optional pull request to initiate a push in return.
 // It returns successful acknowledgement if any synchronization takes place.
+// If entries were omitted because of the size limit, the reply is truncated.
 type PullReply struct {
-	Success bool              `protobuf:"varint,1,opt,name=success" json:"success,omitempty"`
-	Entries map[string]*Entry `protobuf:"bytes,2,rep,name=entries" json:"entries,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Pull    *PullRequest      `protobuf:"bytes,3,opt,name=pull" json:"pull,omitempty"`
+	Success   bool              `protobuf:"varint,1,opt,name=success" json:"success,omitempty"`
+	Entries   map[string]*Entry `protobuf:"bytes,2,rep,name=entries" json:"entries,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Pull      *PullRequest      `protobuf:"bytes,3,opt,name=pull" json:"pull,omitempty"`
+	Members   []*Member         `protobuf:"bytes,4,rep,name=members" json:"members,omitempty"`
+	Truncated bool              `protobuf:"varint,5,opt,name=truncated" json:"truncated,omitempty"`
 }
 
 func (m *PullReply) Reset()                    { *m = PullReply{} }
 func (m *PullReply) String() string            { return proto.CompactTextString(m) }
 func (*PullReply) ProtoMessage()               {}
-func (*PullReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*PullReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{3} }
 
 func (m *PullReply) GetSuccess() bool {
 	if m != nil {
@@ -161,6 +183,20 @@ func (m *PullReply) GetPull() *PullRequest {
 	return nil
 }
 
+func (m *PullReply) GetMembers() []*Member {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *PullReply) GetTruncated() bool {
+	if m != nil {
+		return m.Truncated
+	}
+	return false
+}
+
 // PushRequest sends a vector of entries to a remote expecting them to be
 // synchronized at the remote namespace.
 type PushRequest struct {
@@ -170,7 +206,7 @@ type PushRequest struct {
 func (m *PushRequest) Reset()                    { *m = PushRequest{} }
 func (m *PushRequest) String() string            { return proto.CompactTextString(m) }
 func (*PushRequest) ProtoMessage()               {}
-func (*PushRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*PushRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4} }
 
 func (m *PushRequest) GetEntries() map[string]*Entry {
 	if m != nil {
@@ -187,7 +223,7 @@ type PushReply struct {
 func (m *PushReply) Reset()                    { *m = PushReply{} }
 func (m *PushReply) String() string            { return proto.CompactTextString(m) }
 func (*PushReply) ProtoMessage()               {}
-func (*PushReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*PushReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{5} }
 
 func (m *PushReply) GetSuccess() bool {
 	if m != nil {
@@ -196,6 +232,382 @@ func (m *PushReply) GetSuccess() bool {
 	return false
 }
 
+// Member describes a replica in the cluster and its liveness status. The
+// incarnation is only incremented by the member itself and orders conflicting
+// membership updates that are disseminated by gossip. The region is an
+// optional label of the location of the replica.
+type Member struct {
+	Addr        string `protobuf:"bytes,1,opt,name=addr" json:"addr,omitempty"`
+	Pid         uint64 `protobuf:"varint,2,opt,name=pid" json:"pid,omitempty"`
+	Status      uint32 `protobuf:"varint,3,opt,name=status" json:"status,omitempty"`
+	Incarnation uint64 `protobuf:"varint,4,opt,name=incarnation" json:"incarnation,omitempty"`
+	Region      string `protobuf:"bytes,5,opt,name=region" json:"region,omitempty"`
+}
+
+func (m *Member) Reset()                    { *m = Member{} }
+func (m *Member) String() string            { return proto.CompactTextString(m) }
+func (*Member) ProtoMessage()               {}
+func (*Member) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{6} }
+
+func (m *Member) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *Member) GetPid() uint64 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *Member) GetStatus() uint32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *Member) GetIncarnation() uint64 {
+	if m != nil {
+		return m.Incarnation
+	}
+	return 0
+}
+
+func (m *Member) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
+// SyncMessage is streamed in both directions of a streaming synchronization.
+// The initiator streams batches of its version digest, the last of which is
+// done. The remote streams the entries that are later than the digest or
+// missing from it, and the versions it requests to be pushed, followed by a
+// done message. The initiator then streams the requested entries and closes.
+type SyncMessage struct {
+	Versions map[string]*Version `protobuf:"bytes,1,rep,name=versions" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Entries  map[string]*Entry   `protobuf:"bytes,2,rep,name=entries" json:"entries,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Members  []*Member           `protobuf:"bytes,3,rep,name=members" json:"members,omitempty"`
+	Encoding string              `protobuf:"bytes,4,opt,name=encoding" json:"encoding,omitempty"`
+	Done     bool                `protobuf:"varint,5,opt,name=done" json:"done,omitempty"`
+}
+
+func (m *SyncMessage) Reset()                    { *m = SyncMessage{} }
+func (m *SyncMessage) String() string            { return proto.CompactTextString(m) }
+func (*SyncMessage) ProtoMessage()               {}
+func (*SyncMessage) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{7} }
+
+func (m *SyncMessage) GetVersions() map[string]*Version {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+func (m *SyncMessage) GetEntries() map[string]*Entry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *SyncMessage) GetMembers() []*Member {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *SyncMessage) GetEncoding() string {
+	if m != nil {
+		return m.Encoding
+	}
+	return ""
+}
+
+func (m *SyncMessage) GetDone() bool {
+	if m != nil {
+		return m.Done
+	}
+	return false
+}
+
+// SnapshotRequest asks a peer to stream a consistent snapshot of its namespace
+// to bootstrap a new replica from, in chunks of at most limit bytes of entries.
+type SnapshotRequest struct {
+	Cluster  string `protobuf:"bytes,1,opt,name=cluster" json:"cluster,omitempty"`
+	Limit    uint64 `protobuf:"varint,2,opt,name=limit" json:"limit,omitempty"`
+	Encoding string `protobuf:"bytes,3,opt,name=encoding" json:"encoding,omitempty"`
+}
+
+func (m *SnapshotRequest) Reset()                    { *m = SnapshotRequest{} }
+func (m *SnapshotRequest) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotRequest) ProtoMessage()               {}
+func (*SnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{8} }
+
+func (m *SnapshotRequest) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+func (m *SnapshotRequest) GetLimit() uint64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *SnapshotRequest) GetEncoding() string {
+	if m != nil {
+		return m.Encoding
+	}
+	return ""
+}
+
+// SnapshotChunk is a batch of the entries of a snapshot. The first chunk
+// contains the number of keys in the snapshot.
+type SnapshotChunk struct {
+	Entries map[string]*Entry `protobuf:"bytes,1,rep,name=entries" json:"entries,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Keys    uint64            `protobuf:"varint,2,opt,name=keys" json:"keys,omitempty"`
+}
+
+func (m *SnapshotChunk) Reset()                    { *m = SnapshotChunk{} }
+func (m *SnapshotChunk) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotChunk) ProtoMessage()               {}
+func (*SnapshotChunk) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{9} }
+
+func (m *SnapshotChunk) GetEntries() map[string]*Entry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *SnapshotChunk) GetKeys() uint64 {
+	if m != nil {
+		return m.Keys
+	}
+	return 0
+}
+
+// JoinRequest is sent by a new replica to any seed peer in the cluster.
+type JoinRequest struct {
+	Member *Member `protobuf:"bytes,1,opt,name=member" json:"member,omitempty"`
+}
+
+func (m *JoinRequest) Reset()                    { *m = JoinRequest{} }
+func (m *JoinRequest) String() string            { return proto.CompactTextString(m) }
+func (*JoinRequest) ProtoMessage()               {}
+func (*JoinRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{10} }
+
+func (m *JoinRequest) GetMember() *Member {
+	if m != nil {
+		return m.Member
+	}
+	return nil
+}
+
+// JoinReply returns the seed's current view of the cluster membership.
+type JoinReply struct {
+	Success bool      `protobuf:"varint,1,opt,name=success" json:"success,omitempty"`
+	Members []*Member `protobuf:"bytes,2,rep,name=members" json:"members,omitempty"`
+	Error   string    `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *JoinReply) Reset()                    { *m = JoinReply{} }
+func (m *JoinReply) String() string            { return proto.CompactTextString(m) }
+func (*JoinReply) ProtoMessage()               {}
+func (*JoinReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{11} }
+
+func (m *JoinReply) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *JoinReply) GetMembers() []*Member {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *JoinReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+// LeaveRequest announces the graceful departure of a replica from the cluster.
+type LeaveRequest struct {
+	Member *Member `protobuf:"bytes,1,opt,name=member" json:"member,omitempty"`
+}
+
+func (m *LeaveRequest) Reset()                    { *m = LeaveRequest{} }
+func (m *LeaveRequest) String() string            { return proto.CompactTextString(m) }
+func (*LeaveRequest) ProtoMessage()               {}
+func (*LeaveRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{12} }
+
+func (m *LeaveRequest) GetMember() *Member {
+	if m != nil {
+		return m.Member
+	}
+	return nil
+}
+
+// LeaveReply acknowledges that the departure has been recorded.
+type LeaveReply struct {
+	Success bool `protobuf:"varint,1,opt,name=success" json:"success,omitempty"`
+}
+
+func (m *LeaveReply) Reset()                    { *m = LeaveReply{} }
+func (m *LeaveReply) String() string            { return proto.CompactTextString(m) }
+func (*LeaveReply) ProtoMessage()               {}
+func (*LeaveReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{13} }
+
+func (m *LeaveReply) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+// PingRequest probes the liveness of a replica, piggybacking the sender's view
+// of the membership. For indirect probes the target is the address of the
+// replica that the receiver should probe on behalf of the sender.
+type PingRequest struct {
+	Target  string    `protobuf:"bytes,1,opt,name=target" json:"target,omitempty"`
+	Members []*Member `protobuf:"bytes,2,rep,name=members" json:"members,omitempty"`
+}
+
+func (m *PingRequest) Reset()                    { *m = PingRequest{} }
+func (m *PingRequest) String() string            { return proto.CompactTextString(m) }
+func (*PingRequest) ProtoMessage()               {}
+func (*PingRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{14} }
+
+func (m *PingRequest) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *PingRequest) GetMembers() []*Member {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+// PingReply acknowledges a probe, piggybacking the receiver's membership view.
+type PingReply struct {
+	Ack     bool      `protobuf:"varint,1,opt,name=ack" json:"ack,omitempty"`
+	Members []*Member `protobuf:"bytes,2,rep,name=members" json:"members,omitempty"`
+}
+
+func (m *PingReply) Reset()                    { *m = PingReply{} }
+func (m *PingReply) String() string            { return proto.CompactTextString(m) }
+func (*PingReply) ProtoMessage()               {}
+func (*PingReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{15} }
+
+func (m *PingReply) GetAck() bool {
+	if m != nil {
+		return m.Ack
+	}
+	return false
+}
+
+func (m *PingReply) GetMembers() []*Member {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+// HandshakeRequest identifies the replica that is connecting to a peer.
+type HandshakeRequest struct {
+	Pid     uint64 `protobuf:"varint,1,opt,name=pid" json:"pid,omitempty"`
+	Cluster string `protobuf:"bytes,2,opt,name=cluster" json:"cluster,omitempty"`
+	Addr    string `protobuf:"bytes,3,opt,name=addr" json:"addr,omitempty"`
+}
+
+func (m *HandshakeRequest) Reset()                    { *m = HandshakeRequest{} }
+func (m *HandshakeRequest) String() string            { return proto.CompactTextString(m) }
+func (*HandshakeRequest) ProtoMessage()               {}
+func (*HandshakeRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{16} }
+
+func (m *HandshakeRequest) GetPid() uint64 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *HandshakeRequest) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+func (m *HandshakeRequest) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+// HandshakeReply identifies the peer, refusing the connection if the replicas
+// do not belong to the same cluster.
+type HandshakeReply struct {
+	Success bool   `protobuf:"varint,1,opt,name=success" json:"success,omitempty"`
+	Pid     uint64 `protobuf:"varint,2,opt,name=pid" json:"pid,omitempty"`
+	Cluster string `protobuf:"bytes,3,opt,name=cluster" json:"cluster,omitempty"`
+	Error   string `protobuf:"bytes,4,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *HandshakeReply) Reset()                    { *m = HandshakeReply{} }
+func (m *HandshakeReply) String() string            { return proto.CompactTextString(m) }
+func (*HandshakeReply) ProtoMessage()               {}
+func (*HandshakeReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{17} }
+
+func (m *HandshakeReply) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *HandshakeReply) GetPid() uint64 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *HandshakeReply) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+func (m *HandshakeReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Version)(nil), "rpc.Version")
 	proto.RegisterType((*Entry)(nil), "rpc.Entry")
@@ -203,6 +615,18 @@ func init() {
 	proto.RegisterType((*PullReply)(nil), "rpc.PullReply")
 	proto.RegisterType((*PushRequest)(nil), "rpc.PushRequest")
 	proto.RegisterType((*PushReply)(nil), "rpc.PushReply")
+	proto.RegisterType((*Member)(nil), "rpc.Member")
+	proto.RegisterType((*SyncMessage)(nil), "rpc.SyncMessage")
+	proto.RegisterType((*SnapshotRequest)(nil), "rpc.SnapshotRequest")
+	proto.RegisterType((*SnapshotChunk)(nil), "rpc.SnapshotChunk")
+	proto.RegisterType((*JoinRequest)(nil), "rpc.JoinRequest")
+	proto.RegisterType((*JoinReply)(nil), "rpc.JoinReply")
+	proto.RegisterType((*LeaveRequest)(nil), "rpc.LeaveRequest")
+	proto.RegisterType((*LeaveReply)(nil), "rpc.LeaveReply")
+	proto.RegisterType((*PingRequest)(nil), "rpc.PingRequest")
+	proto.RegisterType((*PingReply)(nil), "rpc.PingReply")
+	proto.RegisterType((*HandshakeRequest)(nil), "rpc.HandshakeRequest")
+	proto.RegisterType((*HandshakeReply)(nil), "rpc.HandshakeReply")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -218,6 +642,13 @@ const _ = grpc.SupportPackageIsVersion4
 type GossipClient interface {
 	Push(ctx context.Context, in *PushRequest, opts ...grpc.CallOption) (*PushReply, error)
 	Pull(ctx context.Context, in *PullRequest, opts ...grpc.CallOption) (*PullReply, error)
+	Sync(ctx context.Context, opts ...grpc.CallOption) (Gossip_SyncClient, error)
+	Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (Gossip_SnapshotClient, error)
+	Join(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*JoinReply, error)
+	Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveReply, error)
+	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingReply, error)
+	PingReq(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingReply, error)
+	Handshake(ctx context.Context, in *HandshakeRequest, opts ...grpc.CallOption) (*HandshakeReply, error)
 }
 
 type gossipClient struct {
@@ -246,11 +677,126 @@ func (c *gossipClient) Pull(ctx context.Context, in *PullRequest, opts ...grpc.C
 	return out, nil
 }
 
+func (c *gossipClient) Sync(ctx context.Context, opts ...grpc.CallOption) (Gossip_SyncClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Gossip_serviceDesc.Streams[0], c.cc, "/rpc.Gossip/Sync", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gossipSyncClient{stream}
+	return x, nil
+}
+
+type Gossip_SyncClient interface {
+	Send(*SyncMessage) error
+	Recv() (*SyncMessage, error)
+	grpc.ClientStream
+}
+
+type gossipSyncClient struct {
+	grpc.ClientStream
+}
+
+func (x *gossipSyncClient) Send(m *SyncMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *gossipSyncClient) Recv() (*SyncMessage, error) {
+	m := new(SyncMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gossipClient) Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (Gossip_SnapshotClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Gossip_serviceDesc.Streams[1], c.cc, "/rpc.Gossip/Snapshot", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gossipSnapshotClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Gossip_SnapshotClient interface {
+	Recv() (*SnapshotChunk, error)
+	grpc.ClientStream
+}
+
+type gossipSnapshotClient struct {
+	grpc.ClientStream
+}
+
+func (x *gossipSnapshotClient) Recv() (*SnapshotChunk, error) {
+	m := new(SnapshotChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gossipClient) Join(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*JoinReply, error) {
+	out := new(JoinReply)
+	err := grpc.Invoke(ctx, "/rpc.Gossip/Join", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveReply, error) {
+	out := new(LeaveReply)
+	err := grpc.Invoke(ctx, "/rpc.Gossip/Leave", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingReply, error) {
+	out := new(PingReply)
+	err := grpc.Invoke(ctx, "/rpc.Gossip/Ping", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) PingReq(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingReply, error) {
+	out := new(PingReply)
+	err := grpc.Invoke(ctx, "/rpc.Gossip/PingReq", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) Handshake(ctx context.Context, in *HandshakeRequest, opts ...grpc.CallOption) (*HandshakeReply, error) {
+	out := new(HandshakeReply)
+	err := grpc.Invoke(ctx, "/rpc.Gossip/Handshake", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Gossip service
 
 type GossipServer interface {
 	Push(context.Context, *PushRequest) (*PushReply, error)
 	Pull(context.Context, *PullRequest) (*PullReply, error)
+	Sync(Gossip_SyncServer) error
+	Snapshot(*SnapshotRequest, Gossip_SnapshotServer) error
+	Join(context.Context, *JoinRequest) (*JoinReply, error)
+	Leave(context.Context, *LeaveRequest) (*LeaveReply, error)
+	Ping(context.Context, *PingRequest) (*PingReply, error)
+	PingReq(context.Context, *PingRequest) (*PingReply, error)
+	Handshake(context.Context, *HandshakeRequest) (*HandshakeReply, error)
 }
 
 func RegisterGossipServer(s *grpc.Server, srv GossipServer) {
@@ -293,6 +839,143 @@ func _Gossip_Pull_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Gossip_Sync_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GossipServer).Sync(&gossipSyncServer{stream})
+}
+
+type Gossip_SyncServer interface {
+	Send(*SyncMessage) error
+	Recv() (*SyncMessage, error)
+	grpc.ServerStream
+}
+
+type gossipSyncServer struct {
+	grpc.ServerStream
+}
+
+func (x *gossipSyncServer) Send(m *SyncMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *gossipSyncServer) Recv() (*SyncMessage, error) {
+	m := new(SyncMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Gossip_Snapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SnapshotRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GossipServer).Snapshot(m, &gossipSnapshotServer{stream})
+}
+
+type Gossip_SnapshotServer interface {
+	Send(*SnapshotChunk) error
+	grpc.ServerStream
+}
+
+type gossipSnapshotServer struct {
+	grpc.ServerStream
+}
+
+func (x *gossipSnapshotServer) Send(m *SnapshotChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Gossip_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Join(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Gossip/Join",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Join(ctx, req.(*JoinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_Leave_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Leave(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Gossip/Leave",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Leave(ctx, req.(*LeaveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Gossip/Ping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_PingReq_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).PingReq(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Gossip/PingReq",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).PingReq(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandshakeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Handshake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Gossip/Handshake",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Handshake(ctx, req.(*HandshakeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Gossip_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "rpc.Gossip",
 	HandlerType: (*GossipServer)(nil),
@@ -305,39 +988,102 @@ var _Gossip_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Pull",
 			Handler:    _Gossip_Pull_Handler,
 		},
+		{
+			MethodName: "Join",
+			Handler:    _Gossip_Join_Handler,
+		},
+		{
+			MethodName: "Leave",
+			Handler:    _Gossip_Leave_Handler,
+		},
+		{
+			MethodName: "Ping",
+			Handler:    _Gossip_Ping_Handler,
+		},
+		{
+			MethodName: "PingReq",
+			Handler:    _Gossip_PingReq_Handler,
+		},
+		{
+			MethodName: "Handshake",
+			Handler:    _Gossip_Handshake_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Sync",
+			Handler:       _Gossip_Sync_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Snapshot",
+			Handler:       _Gossip_Snapshot_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "gossip.proto",
 }
 
-func init() { proto.RegisterFile("gossip.proto", fileDescriptor0) }
-
-var fileDescriptor0 = []byte{
-	// 402 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x53, 0xc1, 0x8e, 0x9b, 0x30,
-	0x10, 0x8d, 0x03, 0x81, 0x64, 0xa0, 0x6d, 0x64, 0x55, 0x15, 0x4a, 0xd5, 0x0a, 0xa1, 0xb4, 0x42,
-	0x3d, 0x70, 0x20, 0xaa, 0x5a, 0xe5, 0x9e, 0x56, 0xbd, 0x55, 0x3e, 0xe4, 0x5c, 0x42, 0xad, 0xd6,
-	0x8a, 0x05, 0xae, 0x0d, 0x91, 0xf8, 0x89, 0x9e, 0x76, 0x7f, 0x6a, 0xbf, 0x6a, 0x85, 0x0d, 0x59,
-	0xc2, 0x46, 0x7b, 0xda, 0x9b, 0x67, 0xde, 0x9b, 0x99, 0xf7, 0x5e, 0x02, 0xf8, 0x7f, 0x4a, 0xa5,
-	0x98, 0x48, 0x84, 0x2c, 0xab, 0x12, 0x5b, 0x52, 0xe4, 0xd1, 0x06, 0xdc, 0x3d, 0x95, 0x8a, 0x95,
-	0x05, 0x7e, 0x03, 0x8e, 0xca, 0x33, 0x9e, 0xc9, 0x00, 0x85, 0x28, 0xb6, 0x49, 0x57, 0xe1, 0x25,
-	0x58, 0x82, 0xfd, 0x0e, 0xa6, 0xba, 0xd9, 0x3e, 0xa3, 0x5b, 0x04, 0xb3, 0x5d, 0x51, 0xc9, 0x06,
-	0xaf, 0xc1, 0x11, 0x99, 0xa4, 0x45, 0xa5, 0x67, 0xbc, 0xd4, 0x4f, 0xa4, 0xc8, 0x93, 0x6e, 0x23,
-	0xe9, 0x30, 0xfc, 0x11, 0xdc, 0x93, 0x69, 0xe9, 0x2d, 0x63, 0x5a, 0x0f, 0xe2, 0xd7, 0x30, 0x3b,
-	0x65, 0xbc, 0xa6, 0x81, 0x15, 0xa2, 0xd8, 0x27, 0xa6, 0xc0, 0x31, 0xbc, 0xaa, 0x64, 0x96, 0x1f,
-	0xf7, 0x4c, 0xb1, 0x03, 0xe3, 0xac, 0x6a, 0x02, 0x3b, 0x44, 0xf1, 0x9c, 0x8c, 0xdb, 0xd1, 0x0d,
-	0x02, 0xef, 0x67, 0xcd, 0x39, 0xa1, 0xff, 0x6a, 0xaa, 0x2a, 0xbc, 0x85, 0x79, 0xb7, 0x5a, 0x05,
-	0x28, 0xb4, 0x62, 0x2f, 0x7d, 0xaf, 0x0f, 0x0f, 0x38, 0xbd, 0x08, 0xa5, 0xfd, 0x90, 0x33, 0x7f,
-	0xf5, 0x03, 0x5e, 0x5c, 0x40, 0x6d, 0x0c, 0x47, 0xda, 0x68, 0x9f, 0x0b, 0xd2, 0x3e, 0x71, 0xd4,
-	0xcb, 0xbd, 0x66, 0xca, 0x40, 0xdb, 0xe9, 0x57, 0x14, 0xdd, 0x21, 0x58, 0x98, 0x93, 0x82, 0x37,
-	0x38, 0x00, 0x57, 0xd5, 0x79, 0x4e, 0x95, 0xd2, 0xbb, 0xe6, 0xa4, 0x2f, 0xf1, 0x67, 0x70, 0x69,
-	0x51, 0x49, 0x46, 0x55, 0x30, 0xd5, 0x6a, 0xdf, 0x0e, 0xd4, 0x0a, 0xde, 0x24, 0x3b, 0x83, 0x1a,
-	0xa9, 0x3d, 0x17, 0xaf, 0xc1, 0x16, 0x35, 0xe7, 0x3a, 0x34, 0x2f, 0x5d, 0x8e, 0x1d, 0x12, 0x8d,
-	0xae, 0xbe, 0x81, 0x3f, 0x1c, 0xbf, 0x62, 0x27, 0xbc, 0xb4, 0x03, 0x7a, 0x91, 0xb9, 0x35, 0x30,
-	0xf3, 0x5f, 0x67, 0xac, 0xfe, 0xf6, 0x19, 0x7f, 0x79, 0x10, 0x6d, 0x22, 0x7e, 0xd7, 0x09, 0x38,
-	0x53, 0xae, 0xcb, 0x7e, 0x36, 0x41, 0x1f, 0xda, 0x70, 0xdb, 0x63, 0x4f, 0x86, 0x9b, 0xfe, 0x02,
-	0xe7, 0xbb, 0xfe, 0xf7, 0xe3, 0x4f, 0x60, 0xb7, 0x03, 0x78, 0x39, 0x16, 0xba, 0x7a, 0x39, 0xe8,
-	0x08, 0xde, 0x44, 0x13, 0xc3, 0xe5, 0x1c, 0x3f, 0x4a, 0xf5, 0xcc, 0xed, 0x7e, 0x9b, 0x68, 0x72,
-	0x70, 0xf4, 0x67, 0xb5, 0xb9, 0x0f, 0x00, 0x00, 0xff, 0xff, 0x37, 0x1e, 0x4d, 0xf5, 0x66, 0x03,
+func init() { proto.RegisterFile("gossip.proto", fileDescriptor1) }
+
+var fileDescriptor1 = []byte{
+	// 898 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xdb, 0x6e, 0xe3, 0x44,
+	0x18, 0xae, 0x63, 0x37, 0x89, 0x7f, 0xa7, 0x69, 0x18, 0x96, 0x95, 0x65, 0x58, 0x88, 0xcc, 0xee,
+	0x2a, 0x42, 0xa2, 0xaa, 0x52, 0xa1, 0x3d, 0xdc, 0xc2, 0x72, 0xd2, 0xae, 0xb4, 0xf2, 0x4a, 0xbd,
+	0xe3, 0x62, 0xea, 0x8c, 0x52, 0x2b, 0xee, 0xd8, 0xcc, 0x8c, 0x2b, 0xf9, 0x8e, 0x27, 0x80, 0xb7,
+	0xe0, 0x2d, 0xb8, 0xe6, 0x65, 0xe0, 0x1d, 0xd0, 0x9c, 0x92, 0x71, 0x92, 0x46, 0x45, 0xea, 0xdd,
+	0xfc, 0xe7, 0xff, 0xff, 0xfe, 0x83, 0x0d, 0xa3, 0x65, 0xc5, 0x79, 0x51, 0x9f, 0xd5, 0xac, 0x12,
+	0x15, 0xf2, 0x59, 0x9d, 0xa7, 0x17, 0x30, 0xb8, 0x24, 0x8c, 0x17, 0x15, 0x45, 0x8f, 0xa1, 0xcf,
+	0x73, 0x5c, 0x62, 0x16, 0x7b, 0x53, 0x6f, 0x16, 0x64, 0x86, 0x42, 0x13, 0xf0, 0xeb, 0x62, 0x11,
+	0xf7, 0x14, 0x53, 0x3e, 0xd3, 0xbf, 0x3d, 0x38, 0x7e, 0x43, 0x05, 0x6b, 0xd1, 0x53, 0xe8, 0xd7,
+	0x98, 0x11, 0x2a, 0x94, 0x4d, 0x34, 0x1f, 0x9d, 0xb1, 0x3a, 0x3f, 0x33, 0x1e, 0x33, 0x23, 0x43,
+	0xcf, 0x61, 0x70, 0xab, 0x59, 0x71, 0x6f, 0x8f, 0x9a, 0x15, 0xa2, 0x47, 0x70, 0x7c, 0x8b, 0xcb,
+	0x86, 0xc4, 0xfe, 0xd4, 0x9b, 0x8d, 0x32, 0x4d, 0xa0, 0x19, 0x9c, 0x0a, 0x86, 0xf3, 0xd5, 0x65,
+	0xc1, 0x8b, 0xab, 0xa2, 0x2c, 0x44, 0x1b, 0x07, 0x53, 0x6f, 0x36, 0xcc, 0xb6, 0xd9, 0x28, 0x81,
+	0x21, 0xa1, 0x79, 0xb5, 0x28, 0xe8, 0x32, 0x3e, 0x9e, 0x7a, 0xb3, 0x30, 0x5b, 0xd3, 0xd2, 0xb7,
+	0x54, 0x27, 0x71, 0x5f, 0x09, 0x34, 0x91, 0xfe, 0xeb, 0x41, 0xf4, 0xbe, 0x29, 0xcb, 0x8c, 0xfc,
+	0xda, 0x10, 0x2e, 0xd0, 0x6b, 0x18, 0x9a, 0x64, 0x78, 0xec, 0x4d, 0xfd, 0x59, 0x34, 0xff, 0x5c,
+	0xa5, 0xea, 0xe8, 0xd8, 0xb4, 0xb9, 0x42, 0x20, 0x5b, 0xeb, 0xa3, 0x67, 0x30, 0xb8, 0x21, 0x37,
+	0x57, 0x84, 0xf1, 0xb8, 0xa7, 0x4c, 0x23, 0x65, 0xfa, 0x4e, 0xf1, 0x32, 0x2b, 0x93, 0x89, 0x94,
+	0xc5, 0x4d, 0x21, 0x54, 0x91, 0x41, 0xa6, 0x89, 0x4e, 0xea, 0x41, 0x37, 0xf5, 0xe4, 0x27, 0x38,
+	0xe9, 0xc4, 0x94, 0x1d, 0x59, 0x91, 0x56, 0x41, 0x1e, 0x66, 0xf2, 0x89, 0x52, 0x8b, 0xdc, 0x3e,
+	0x7c, 0xb5, 0xe8, 0x75, 0xef, 0xa5, 0x97, 0xfe, 0xd1, 0x83, 0x50, 0xd7, 0x52, 0x97, 0x2d, 0x8a,
+	0x61, 0xc0, 0x9b, 0x3c, 0x27, 0x9c, 0x2b, 0x5f, 0xc3, 0xcc, 0x92, 0xe8, 0x1b, 0x18, 0x10, 0x2a,
+	0x58, 0x41, 0x6c, 0x2d, 0x9f, 0x3a, 0x30, 0xd4, 0x65, 0x7b, 0xf6, 0x46, 0x4b, 0x35, 0x06, 0x56,
+	0x17, 0x3d, 0x85, 0xa0, 0x6e, 0xca, 0x52, 0x95, 0x16, 0xcd, 0x27, 0xdb, 0xd0, 0x65, 0x4a, 0xea,
+	0x02, 0x15, 0x1c, 0x00, 0xea, 0x33, 0x08, 0x05, 0x6b, 0x68, 0x8e, 0x05, 0x59, 0xa8, 0x76, 0x0e,
+	0xb3, 0x0d, 0x23, 0xf9, 0x1e, 0x46, 0x6e, 0x0e, 0x7b, 0x30, 0x99, 0x76, 0x31, 0x01, 0x15, 0x44,
+	0x27, 0xec, 0x20, 0xf2, 0xbb, 0x9a, 0x00, 0x7e, 0x6d, 0x27, 0xe0, 0xc5, 0xa6, 0x72, 0x3d, 0x00,
+	0x4f, 0x4c, 0x15, 0x6b, 0x95, 0xfd, 0xb5, 0x3f, 0x58, 0x42, 0xcf, 0x20, 0xd4, 0xc1, 0x0e, 0x76,
+	0x28, 0xfd, 0xcd, 0x83, 0xbe, 0x46, 0x0c, 0x21, 0x08, 0xf0, 0x62, 0xc1, 0x4c, 0x28, 0xf5, 0xde,
+	0x5d, 0x5a, 0xb5, 0xde, 0x02, 0x8b, 0x86, 0xab, 0xee, 0x9c, 0x64, 0x86, 0x42, 0x53, 0x88, 0x0a,
+	0x9a, 0x63, 0x46, 0xb1, 0x90, 0x0b, 0x1a, 0x28, 0x0b, 0x97, 0x25, 0x2d, 0x19, 0x59, 0x4a, 0xa1,
+	0x5e, 0x2a, 0x43, 0xa5, 0xff, 0xf4, 0x20, 0xfa, 0xd0, 0xd2, 0xfc, 0x1d, 0xe1, 0x1c, 0x2f, 0xc9,
+	0x9d, 0xcb, 0xe3, 0xe8, 0xdc, 0xb9, 0x3c, 0x2f, 0xb6, 0x07, 0xee, 0xc9, 0x8e, 0xe9, 0xfe, 0x91,
+	0x73, 0x86, 0xc9, 0x3f, 0x30, 0x4c, 0x07, 0xf6, 0x4b, 0xe2, 0xb7, 0xa8, 0x28, 0x31, 0x33, 0xa6,
+	0xde, 0x0f, 0xb8, 0x73, 0x0f, 0x36, 0x18, 0xbf, 0xc0, 0xe9, 0x07, 0x8a, 0x6b, 0x7e, 0x5d, 0x09,
+	0x3b, 0xac, 0x31, 0x0c, 0xf2, 0xb2, 0xe1, 0x82, 0xd8, 0xe6, 0x5b, 0x72, 0x73, 0x65, 0x7a, 0x77,
+	0x5d, 0x19, 0xbf, 0x8b, 0x42, 0xfa, 0xa7, 0x07, 0x27, 0xd6, 0xff, 0xb7, 0xd7, 0x0d, 0x5d, 0xa1,
+	0x57, 0xdb, 0xab, 0xf0, 0x85, 0xee, 0x89, 0xab, 0x74, 0x47, 0x57, 0x10, 0x04, 0x2b, 0xd2, 0x72,
+	0x13, 0x5d, 0xbd, 0x1f, 0x0c, 0x87, 0x39, 0x44, 0x3f, 0x57, 0x05, 0xb5, 0x18, 0x7c, 0x09, 0x7d,
+	0xdd, 0x64, 0xf3, 0x09, 0xea, 0xf4, 0xdf, 0x88, 0xd2, 0x2b, 0x08, 0xb5, 0xcd, 0xe1, 0xb3, 0x77,
+	0xff, 0x13, 0x4e, 0x18, 0xab, 0x98, 0xc1, 0x50, 0x13, 0xe9, 0x05, 0x8c, 0xde, 0x12, 0x7c, 0x4b,
+	0xfe, 0x57, 0x62, 0xcf, 0x01, 0x8c, 0xd1, 0xe1, 0x75, 0x7f, 0x0b, 0xd1, 0xfb, 0x82, 0x2e, 0xad,
+	0xef, 0xc7, 0xd0, 0x17, 0x98, 0x2d, 0x89, 0x30, 0xf0, 0x19, 0xea, 0x9e, 0x05, 0xa4, 0xdf, 0x41,
+	0xa8, 0xbd, 0xc9, 0xa0, 0x13, 0xf0, 0x71, 0xbe, 0x32, 0x01, 0xe5, 0xf3, 0xbe, 0x5e, 0x32, 0x98,
+	0xfc, 0x88, 0xe9, 0x82, 0x5f, 0xe3, 0xd5, 0xba, 0x68, 0x73, 0x77, 0xbc, 0xcd, 0xdd, 0x71, 0x66,
+	0xb4, 0xd7, 0x9d, 0x51, 0x7b, 0xb7, 0xfc, 0xcd, 0xdd, 0x4a, 0x4b, 0x18, 0x3b, 0x3e, 0x0f, 0x77,
+	0x6b, 0xf7, 0xc6, 0x39, 0xb1, 0xfc, 0x9d, 0x7d, 0xd0, 0x2d, 0x0b, 0x9c, 0x96, 0xcd, 0xff, 0xf2,
+	0xa1, 0xff, 0x83, 0xfa, 0x27, 0x42, 0x5f, 0x41, 0x20, 0xcf, 0x2e, 0x9a, 0x6c, 0x9f, 0xfb, 0x64,
+	0xec, 0x70, 0xea, 0xb2, 0x4d, 0x8f, 0xb4, 0x6e, 0x59, 0xa2, 0x9d, 0x0f, 0x5c, 0x32, 0x76, 0x38,
+	0x5a, 0xf7, 0x1c, 0x02, 0x79, 0xc4, 0x8c, 0xae, 0x73, 0xcf, 0x92, 0x1d, 0x4e, 0x7a, 0x34, 0xf3,
+	0xce, 0x3d, 0xf4, 0x12, 0x86, 0x76, 0xc5, 0xd0, 0xa3, 0xce, 0xc6, 0xd9, 0x28, 0x68, 0x77, 0x0f,
+	0xd3, 0xa3, 0x73, 0x4f, 0xe6, 0x25, 0xa7, 0xdc, 0xc4, 0x72, 0x96, 0x24, 0x19, 0x3b, 0x1c, 0x9d,
+	0xd7, 0xd7, 0x70, 0xac, 0x06, 0x0f, 0x7d, 0xa4, 0x44, 0xee, 0xe4, 0x26, 0xa7, 0x2e, 0x6b, 0x53,
+	0xb2, 0xbc, 0x95, 0xa6, 0xe4, 0xcd, 0x28, 0x26, 0x63, 0x87, 0x63, 0x5d, 0x0f, 0x8c, 0xc2, 0xbd,
+	0xd4, 0x5f, 0x41, 0xb8, 0x6e, 0x39, 0xfa, 0x44, 0x89, 0xb7, 0xc7, 0x2a, 0xf9, 0x78, 0x9b, 0xad,
+	0x4c, 0xaf, 0xfa, 0xea, 0x4f, 0xf6, 0xe2, 0xbf, 0x01, 0x00, 0x07, 0x25, 0x19, 0x71, 0xd9, 0x0a,
 	0x00, 0x00,
 }
@@ -25,7 +25,7 @@ type GetRequest struct {
 func (m *GetRequest) Reset()                    { *m = GetRequest{} }
 func (m *GetRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetRequest) ProtoMessage()               {}
-func (*GetRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
+func (*GetRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{0} }
 
 func (m *GetRequest) GetKey() string {
 	if m != nil {
@@ -46,7 +46,7 @@ type GetReply struct {
 func (m *GetReply) Reset()                    { *m = GetReply{} }
 func (m *GetReply) String() string            { return proto.CompactTextString(m) }
 func (*GetReply) ProtoMessage()               {}
-func (*GetReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
+func (*GetReply) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{1} }
 
 func (m *GetReply) GetSuccess() bool {
 	if m != nil {
@@ -93,7 +93,7 @@ type PutRequest struct {
 func (m *PutRequest) Reset()                    { *m = PutRequest{} }
 func (m *PutRequest) String() string            { return proto.CompactTextString(m) }
 func (*PutRequest) ProtoMessage()               {}
-func (*PutRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2} }
+func (*PutRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{2} }
 
 func (m *PutRequest) GetKey() string {
 	if m != nil {
@@ -127,7 +127,7 @@ type PutReply struct {
 func (m *PutReply) Reset()                    { *m = PutReply{} }
 func (m *PutReply) String() string            { return proto.CompactTextString(m) }
 func (*PutReply) ProtoMessage()               {}
-func (*PutReply) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{3} }
+func (*PutReply) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{3} }
 
 func (m *PutReply) GetSuccess() bool {
 	if m != nil {
@@ -269,25 +269,24 @@ var _Storage_serviceDesc = grpc.ServiceDesc{
 	Metadata: "service.proto",
 }
 
-func init() { proto.RegisterFile("service.proto", fileDescriptor1) }
-
-var fileDescriptor1 = []byte{
-	// 260 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x91, 0xc1, 0x4b, 0xc3, 0x30,
-	0x14, 0xc6, 0xb7, 0x76, 0x73, 0xf5, 0xe1, 0x98, 0x04, 0x0f, 0xc1, 0x83, 0x8c, 0x9c, 0x7a, 0x90,
-	0x1e, 0xf4, 0x8f, 0xf0, 0x3a, 0x22, 0xec, 0x28, 0x74, 0xe1, 0x29, 0x61, 0xc5, 0xc4, 0x97, 0xa4,
-	0x50, 0xf0, 0x8f, 0x97, 0xa6, 0xeb, 0xd2, 0x09, 0x7a, 0xeb, 0xf7, 0xde, 0xd7, 0xef, 0xbd, 0xdf,
-	0x0b, 0xac, 0x1d, 0x52, 0xab, 0x15, 0x56, 0x96, 0x8c, 0x37, 0x2c, 0x27, 0xab, 0xc4, 0x03, 0xc0,
-	0x0b, 0x7a, 0x89, 0x5f, 0x01, 0x9d, 0x67, 0xb7, 0x90, 0x1f, 0xb1, 0xe3, 0xf3, 0xed, 0xbc, 0xbc,
-	0x96, 0xfd, 0xa7, 0xf8, 0x86, 0x22, 0xf6, 0x6d, 0xd3, 0x31, 0x0e, 0x2b, 0x17, 0x94, 0x42, 0xe7,
-	0xa2, 0xa3, 0x90, 0xa3, 0xec, 0x3b, 0x2d, 0x92, 0xd3, 0xe6, 0x93, 0x67, 0xf1, 0xdf, 0x51, 0x8e,
-	0x89, 0xf9, 0x39, 0x91, 0xdd, 0xc1, 0xb2, 0xad, 0x9b, 0x80, 0x7c, 0xb1, 0x9d, 0x97, 0x37, 0x72,
-	0x10, 0x7d, 0x15, 0x89, 0x0c, 0xf1, 0x65, 0x74, 0x0e, 0x42, 0xbc, 0x01, 0xec, 0xc2, 0xdf, 0xdb,
-	0xa5, 0xac, 0x6c, 0x9a, 0x55, 0xc2, 0xc6, 0x53, 0xad, 0x8e, 0x7b, 0xed, 0xf4, 0x41, 0x37, 0xda,
-	0x0f, 0xf3, 0x0b, 0xf9, 0xbb, 0x2c, 0xde, 0xa1, 0x88, 0xf9, 0xff, 0xd3, 0x9d, 0xe6, 0x66, 0x69,
-	0xee, 0x84, 0x37, 0xbf, 0xe4, 0x3d, 0x73, 0x2c, 0x26, 0x1c, 0x4f, 0x08, 0xab, 0x57, 0x6f, 0xa8,
-	0xfe, 0x40, 0xf6, 0x18, 0x0f, 0xba, 0x8f, 0x8b, 0x6e, 0x2a, 0xb2, 0xaa, 0x4a, 0xf7, 0xbf, 0x5f,
-	0xa7, 0x82, 0x6d, 0x3a, 0x31, 0xeb, 0xdd, 0xbb, 0x70, 0xe1, 0x4e, 0xf7, 0x38, 0xb9, 0x47, 0x00,
-	0x31, 0x3b, 0x5c, 0xc5, 0x87, 0x7d, 0xfe, 0x09, 0x00, 0x00, 0xff, 0xff, 0xd7, 0x36, 0x3a, 0x3a,
-	0xe9, 0x01, 0x00, 0x00,
+func init() { proto.RegisterFile("service.proto", fileDescriptor2) }
+
+var fileDescriptor2 = []byte{
+	// 256 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x91, 0x41, 0x4b, 0xc3, 0x40,
+	0x10, 0x85, 0x9b, 0xa4, 0xb5, 0x71, 0xb0, 0x54, 0x16, 0x0f, 0x8b, 0x07, 0x29, 0x7b, 0xca, 0x41,
+	0x72, 0xd0, 0x1f, 0xe1, 0x35, 0xac, 0xd0, 0xa3, 0x90, 0x2e, 0xa3, 0x2c, 0x0d, 0xee, 0x3a, 0xbb,
+	0x1b, 0x08, 0xf8, 0xe3, 0x25, 0x1b, 0xd3, 0xa4, 0x05, 0xf1, 0x96, 0x37, 0xf3, 0xf2, 0x66, 0xbe,
+	0x59, 0xd8, 0x38, 0xa4, 0x56, 0x2b, 0x2c, 0x2d, 0x19, 0x6f, 0x58, 0x46, 0x56, 0x89, 0x07, 0x80,
+	0x17, 0xf4, 0x12, 0xbf, 0x02, 0x3a, 0xcf, 0x6e, 0x21, 0x3b, 0x62, 0xc7, 0x93, 0x5d, 0x52, 0x5c,
+	0xcb, 0xfe, 0x53, 0x7c, 0x43, 0x1e, 0xfb, 0xb6, 0xe9, 0x18, 0x87, 0xb5, 0x0b, 0x4a, 0xa1, 0x73,
+	0xd1, 0x91, 0xcb, 0x51, 0xf6, 0x9d, 0x16, 0xc9, 0x69, 0xf3, 0xc9, 0xd3, 0xf8, 0xef, 0x28, 0xc7,
+	0xc4, 0xec, 0x94, 0xc8, 0xee, 0x60, 0xd5, 0xd6, 0x4d, 0x40, 0xbe, 0xdc, 0x25, 0xc5, 0x8d, 0x1c,
+	0x44, 0x5f, 0x45, 0x22, 0x43, 0x7c, 0x15, 0x9d, 0x83, 0x10, 0x6f, 0x00, 0x55, 0xf8, 0x7b, 0xbb,
+	0x29, 0x2b, 0x9d, 0x67, 0x15, 0xb0, 0xf5, 0x54, 0xab, 0xe3, 0x5e, 0x3b, 0x7d, 0xd0, 0x8d, 0xf6,
+	0xc3, 0xfc, 0x5c, 0x5e, 0x96, 0xc5, 0x3b, 0xe4, 0x55, 0xf8, 0x97, 0xee, 0x77, 0x6e, 0x3a, 0xcd,
+	0x9d, 0xf1, 0x66, 0xe7, 0xbc, 0x27, 0x8e, 0xe5, 0x8c, 0xe3, 0x09, 0x61, 0xfd, 0xea, 0x0d, 0xd5,
+	0x1f, 0xc8, 0x1e, 0xe3, 0x41, 0xf7, 0x71, 0xd1, 0x6d, 0x49, 0x56, 0x95, 0xd3, 0xfd, 0xef, 0x37,
+	0x53, 0xc1, 0x36, 0x9d, 0x58, 0xf4, 0xee, 0x2a, 0x9c, 0xb9, 0xab, 0x70, 0xe1, 0x1e, 0x01, 0xc4,
+	0xe2, 0x70, 0x15, 0x1f, 0xf6, 0xf9, 0x67, 0x00, 0xd7, 0x36, 0x3a, 0x3a, 0xe9, 0x01, 0x00, 0x00,
 }
@@ -0,0 +1,168 @@
+package honu
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/bbengfort/x/stats"
+)
+
+//===========================================================================
+// Adaptive Anti-Entropy Scheduling
+//===========================================================================
+
+// AdaptiveBackoff is the factor the anti-entropy interval is multiplied or
+// divided by when synchronization is idle or busy.
+const AdaptiveBackoff = 2.0
+
+// AdaptiveHistorySize is the number of recent intervals kept in the metrics.
+const AdaptiveHistorySize = 1000
+
+// AdaptiveScheduler determines the interval before the next anti-entropy
+// session from the outcome of the last one. When the last session exchanged
+// multiple versions, or the local write rate is above the busy threshold,
+// the interval is shortened toward the minimum; when the last session was
+// empty, the interval is backed off exponentially toward the maximum.
+// Jitter randomizes every interval so that replicas do not synchronize in
+// lock step, though never below the minimum.
+type AdaptiveScheduler struct {
+	sync.Mutex
+	Minimum    time.Duration    // The shortest interval between sessions
+	Maximum    time.Duration    // The longest interval between sessions
+	Jitter     float64          // Fraction of the interval to randomize by
+	BusyWrites float64          // Local writes per second above which syncs are sped up
+	Shortened  uint64           // The number of times the interval was shortened
+	Lengthened uint64           // The number of times the interval was backed off
+	Intervals  *stats.Benchmark // The distribution of the chosen intervals
+	recent     []time.Duration  // The most recent chosen intervals in a ring buffer
+	next       int              // The index of the oldest interval once the buffer is full
+	current    time.Duration    // The interval before jitter is applied
+	writes     uint64           // The number of local writes at the last session
+	last       time.Time        // The time of the last session
+}
+
+// AdaptiveDelay configures the server to adapt the anti-entropy interval
+// between the minimum and maximum, starting from the replication delay.
+// The jitter must be in [0, 1) so that intervals are always positive.
+// Must be called after Replicate.
+func (s *Server) AdaptiveDelay(minimum, maximum time.Duration, jitter, busyWrites float64) error {
+	if minimum <= 0 || minimum > maximum {
+		return fmt.Errorf("minimum delay must be positive and less than the maximum delay")
+	}
+
+	if jitter < 0 || jitter >= 1 {
+		return fmt.Errorf("delay jitter must be in [0, 1), not %f", jitter)
+	}
+
+	current := s.delay
+	if current < minimum {
+		current = minimum
+	}
+	if current > maximum {
+		current = maximum
+	}
+
+	s.scheduler = &AdaptiveScheduler{
+		Minimum:    minimum,
+		Maximum:    maximum,
+		Jitter:     jitter,
+		BusyWrites: busyWrites,
+		Intervals:  new(stats.Benchmark),
+		recent:     make([]time.Duration, 0, AdaptiveHistorySize),
+		current:    current,
+		last:       time.Now(),
+	}
+
+	info("adapting anti-entropy interval between %s and %s", minimum, maximum)
+	return nil
+}
+
+// Next returns the interval before the next anti-entropy session given the
+// number of versions exchanged in the last session and the total number of
+// local writes.
+func (a *AdaptiveScheduler) Next(items, writes uint64) time.Duration {
+	a.Lock()
+	defer a.Unlock()
+
+	now := time.Now()
+	rate := 0.0
+	if elapsed := now.Sub(a.last).Seconds(); elapsed > 0 && writes >= a.writes {
+		rate = float64(writes-a.writes) / elapsed
+	}
+	a.writes = writes
+	a.last = now
+
+	switch {
+	case items > 1 || (a.BusyWrites > 0 && rate >= a.BusyWrites):
+		// Synchronize more frequently while the replicas are busy
+		a.current = time.Duration(float64(a.current) / AdaptiveBackoff)
+		if a.current < a.Minimum {
+			a.current = a.Minimum
+		}
+		a.Shortened++
+	case items == 0:
+		// Back off exponentially while there is nothing to synchronize
+		a.current = time.Duration(float64(a.current) * AdaptiveBackoff)
+		if a.current > a.Maximum {
+			a.current = a.Maximum
+		}
+		a.Lengthened++
+	}
+
+	interval := a.current
+	if a.Jitter > 0 {
+		interval = time.Duration(float64(interval) * (1 + a.Jitter*(2*rand.Float64()-1)))
+		if interval < a.Minimum {
+			interval = a.Minimum
+		}
+	}
+
+	a.Intervals.Update(interval)
+	if len(a.recent) < cap(a.recent) {
+		a.recent = append(a.recent, interval)
+	} else {
+		a.recent[a.next] = interval
+		a.next = (a.next + 1) % len(a.recent)
+	}
+	return interval
+}
+
+// Serialize the scheduler metrics to write to disk
+func (a *AdaptiveScheduler) Serialize() map[string]interface{} {
+	a.Lock()
+	defer a.Unlock()
+
+	data := make(map[string]interface{})
+	data["Minimum"] = a.Minimum.String()
+	data["Maximum"] = a.Maximum.String()
+	data["Jitter"] = a.Jitter
+	data["BusyWrites"] = a.BusyWrites
+	data["Current"] = a.current.String()
+	data["Shortened"] = a.Shortened
+	data["Lengthened"] = a.Lengthened
+	data["Intervals"] = a.Intervals.Serialize()
+
+	// The most recent intervals in seconds from the oldest
+	recent := make([]float64, 0, len(a.recent))
+	for i := range a.recent {
+		recent = append(recent, a.recent[(a.next+i)%len(a.recent)].Seconds())
+	}
+	data["RecentIntervals"] = recent
+	return data
+}
+
+// nextDelay returns the interval before the next anti-entropy session,
+// which is fixed unless an adaptive scheduler has been configured.
+func (s *Server) nextDelay(session *Synchronization) time.Duration {
+	if s.scheduler == nil {
+		return s.delay
+	}
+
+	s.Lock()
+	writes := s.writes
+	s.Unlock()
+
+	return s.scheduler.Next(session.Items(), writes)
+}
@@ -1,9 +1,9 @@
 package honu
 
 import (
+	"errors"
 	"fmt"
 	"net"
-	"os"
 	"strings"
 	"sync"
 	"time"
@@ -21,10 +21,26 @@ import (
 // DefaultAddr that the honu server listens on.
 const DefaultAddr = ":3264"
 
+// DefaultShutdownTimeout is the time the server waits for in-flight RPCs and
+// anti-entropy sessions to finish when it shuts down.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// ErrServerStopped is returned by Serve if the server was stopped before it
+// started serving.
+var ErrServerStopped = errors.New("server has been stopped")
+
 // NewServer creates and initializes a server.
 func NewServer(pid uint64, sequential bool) *Server {
 	server := new(Server)
 	server.store = NewStore(pid, sequential)
+	server.members = NewMembership(pid)
+	server.reward = new(DefaultReward)
+	server.limit = DefaultMessageLimit
+	server.latency = Timings{"read": new(Histogram), "write": new(Histogram)}
+	server.grace = DefaultShutdownTimeout
+	server.done = make(chan struct{})
+	server.ctx = context.Background()
+	server.encodings = make(map[string]Codec)
 
 	// Save the server type for analytics
 	// TODO: refactor to use reflect to check the name of the struct.
@@ -41,65 +57,299 @@ func NewServer(pid uint64, sequential bool) *Server {
 // in a thread-safe fashion (because the store is surrounded by locks).
 type Server struct {
 	sync.Mutex
-	store      Store             // The in-memory key/value store
-	addr       string            // The IP address of the local server
-	peers      []string          // IP addresses of replica peers
-	delay      time.Duration     // The anti-entropy delay
-	stype      string            // The type of storage being used
-	started    time.Time         // The time the first message was received
-	finished   time.Time         // The time of the last message to be received
-	reads      uint64            // The number of reads to the server
-	writes     uint64            // The number of writes to the server
-	syncs      Syncs             // Per-peer metrics of anti-entropy synchronizations
-	bandit     BanditStrategy    // Peer selection bandit strategy
-	stats      string            // Path to write metrics to
-	history    string            // Path to write version history to
-	visibility *VisibilityLogger // Track the visibility of writes
+	store      Store              // The in-memory key/value store
+	addr       string             // The IP address of the local server
+	cluster    string             // The identifier of the cluster the server belongs to
+	peers      []string           // IP addresses of replica peers (indexed by bandit arm)
+	seeds      []string           // IP addresses of peers to join the cluster through
+	bootstrap  string             // IP address of the peer to load a snapshot from on start
+	members    *Membership        // Dynamic membership of the replica cluster
+	detector   *FailureDetector   // SWIM failure detection of replica peers
+	delay      time.Duration      // The anti-entropy delay
+	codec      Codec              // Compresses values that are gossiped (nil for none)
+	encodings  map[string]Codec   // The codec each peer accepts pushed values in
+	limit      uint64             // Maximum bytes of entries per gossip message
+	budget     float64            // Bytes per second exchanged with each peer (0 for unlimited)
+	handoff    *HintedHandoff     // Pushes writes eagerly and hints them for unreachable peers
+	repair     *ReadRepair        // Repairs the versions of peers in the background of reads
+	rpcs       *RPCMetrics        // Counts the requests and latency of RPCs (nil unless serving metrics)
+	exporter   net.Listener       // Serves live metrics over HTTP (nil unless serving metrics)
+	streaming  bool               // Synchronize with peers over bidirectional streams
+	scheduler  *AdaptiveScheduler // Adapts the anti-entropy delay if specified
+	stype      string             // The type of storage being used
+	running    time.Time          // The time the server started running
+	started    time.Time          // The time the first message was received
+	finished   time.Time          // The time of the last message to be received
+	reads      uint64             // The number of reads to the server
+	writes     uint64             // The number of writes to the server
+	latency    Timings            // The time taken to serve reads and writes
+	syncs      Syncs              // Per-peer metrics of anti-entropy synchronizations
+	bandit     BanditStrategy     // Peer selection bandit strategy
+	reward     RewardFunction     // Reward of anti-entropy sessions for the bandit
+	checkpoint *checkpointer      // Saves and restores what the bandit has learned
+	banditlog  *BanditLogger      // Streams the rewards of the bandit to disk
+	report     *serverReport      // Appends the metrics of every interval to disk
+	stats      string             // Path to write metrics to
+	history    string             // Path to write version history to
+	visibility *VisibilityLogger  // Track the visibility of writes
+	tracer     *Tracer            // Records spans of writes as they propagate (nil for none)
+	dialer     Dialer             // Connects to peers (nil for TCP)
+	faults     *FaultInjector     // Emulates the network between peers (nil for none)
+	srv        *grpc.Server       // Serves the RPCs of the replica
+	entropy    *time.Timer        // Schedules the next anti-entropy session
+	sessions   sync.WaitGroup     // Anti-entropy sessions that are in flight
+	grace      time.Duration      // Time to wait for in-flight work on shutdown
+	uptime     time.Duration      // Time to run for before shutting down (0 for forever)
+	ctx        context.Context    // Cancelled when the server is stopped
+	stop       context.CancelFunc // Stops the server once it is running
+	stopped    bool               // If the server has been shut down
+	done       chan struct{}      // Closed when the server has been shut down
+	err        error              // The error that occurred shutting down
+}
+
+//===========================================================================
+// Peer connections
+//===========================================================================
+
+// Dialer connects to the address of a peer within the timeout.
+type Dialer func(addr string, timeout time.Duration) (net.Conn, error)
+
+// dial connects to the peer at the address, blocking until the connection is
+// established, the connection timeout expires or the context is cancelled.
+// Options that are specified override the default options.
+func (s *Server) dial(ctx context.Context, addr string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	defaults := []grpc.DialOption{
+		grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(timeout),
+	}
+
+	if s.dialer != nil {
+		defaults = append(defaults, grpc.WithDialer(s.dialer), grpc.FailOnNonTempDialError(true))
+	}
+
+	if s.faults != nil {
+		defaults = append(
+			defaults,
+			grpc.WithUnaryInterceptor(s.faults.UnaryInterceptor(addr)),
+			grpc.WithStreamInterceptor(s.faults.StreamInterceptor(addr)),
+		)
+	}
+
+	return grpc.DialContext(ctx, addr, append(defaults, opts...)...)
 }
 
 //===========================================================================
 // Run the server
 //===========================================================================
 
-// Run the Honu server.
-func (s *Server) Run(addr string) error {
+// Run the Honu server on the address until the context is cancelled, the
+// server is stopped or its uptime has passed, then shut it down. Returns the
+// error that stopped the server from serving or that occurred shutting down.
+func (s *Server) Run(ctx context.Context, addr string) error {
 	// Use the default address to run on if one isn't specified.
 	if addr == "" {
 		addr = DefaultAddr
 	}
 
-	// Store the addr on the server
-	s.addr = addr
-
 	// Create the TCP channel to receive connections
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		return fmt.Errorf("could not listen on %s: %s", addr, err.Error())
 	}
 
-	// Create the gRPC handler for RPC messages
-	srv := grpc.NewServer()
+	s.addr = addr
+	return s.Serve(ctx, lis)
+}
+
+// Serve the Honu server on the listener, which may be provided by a process
+// that embeds several replicas, until the context is cancelled, the server
+// is stopped or its uptime has passed. The server is advertised at the
+// address it was run on, or otherwise the address of the listener.
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	// The context of the anti-entropy sessions, which is cancelled when the
+	// server is stopped or the in-flight sessions have been waited for.
+	var stop context.CancelFunc
+	if s.uptime > 0 {
+		ctx, stop = context.WithTimeout(ctx, s.uptime)
+	} else {
+		ctx, stop = context.WithCancel(ctx)
+	}
+
+	// Refuse to serve if the server was stopped before it started serving
+	s.Lock()
+	if s.stopped {
+		s.Unlock()
+		stop()
+		lis.Close()
+		return ErrServerStopped
+	}
+	s.ctx, s.stop = ctx, stop
+	s.Unlock()
+
+	// Store the addr on the server
+	if s.addr == "" {
+		s.addr = lis.Addr().String()
+	}
+	s.running = time.Now()
+	s.members.Advertise(advertise(s.addr))
+
+	// Start emulating the network between peers if specified
+	if s.faults != nil {
+		s.faults.Start(advertise(s.addr))
+	}
+
+	// Load a snapshot of the namespace from the bootstrap peer if specified
+	if s.bootstrap != "" {
+		if err := s.bootstrapFrom(ctx, s.bootstrap); err != nil {
+			warne(err)
+		}
+	}
+
+	// Join the cluster through the seeds if specified
+	if len(s.seeds) > 0 && s.bandit != nil {
+		if err := s.joinCluster(); err != nil {
+			warne(err)
+		}
+	}
+
+	// Start detecting failed peers if specified
+	if s.detector != nil && s.bandit != nil {
+		s.detector.Run()
+	}
+
+	// Start reporting the metrics of every interval if specified
+	if s.report != nil {
+		s.report.reporter.Start()
+	}
+
+	// Schedule the anti-entropy delay
+	if s.bandit != nil {
+		s.scheduleAntiEntropy(s.delay)
+	}
+
+	// Create the gRPC handler for RPC messages, injecting faults into the
+	// requests of clients, tracing the requests if specified and observing
+	// them if metrics are being served
+	var unary []grpc.UnaryServerInterceptor
+	var streams []grpc.StreamServerInterceptor
+	if s.faults != nil {
+		unary = append(unary, s.faults.StorageInterceptor)
+	}
+
+	if s.tracer != nil {
+		unary = append(unary, s.tracer.UnaryInterceptor)
+		streams = append(streams, s.tracer.StreamInterceptor)
+	}
+
+	if s.rpcs != nil {
+		unary = append(unary, s.rpcs.UnaryInterceptor)
+		streams = append(streams, s.rpcs.StreamInterceptor)
+	}
+
+	var opts []grpc.ServerOption
+	if len(unary) > 0 {
+		opts = append(opts, grpc.UnaryInterceptor(chainUnary(unary...)))
+		opts = append(opts, grpc.StreamInterceptor(chainStream(streams...)))
+	}
+
+	srv := grpc.NewServer(opts...)
 	pb.RegisterStorageServer(srv, s)
 	pb.RegisterGossipServer(srv, s)
+	pb.RegisterAdminServer(srv, s)
+
+	s.Lock()
+	s.srv = srv
+	s.Unlock()
+
+	// Shutdown when the context is done, unless the server stops serving
+	go func() {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				info("shutting down server after %s uptime", s.uptime)
+			}
+			s.Shutdown()
+		case <-s.done:
+		}
+	}()
 
-	// Capture interrupt and shutdown gracefully
-	go signalHandler(s.Shutdown)
+	status("honu storage server listening on %s", s.addr)
+	err := srv.Serve(lis)
 
-	status("honu storage server listening on %s", addr)
-	srv.Serve(lis)
+	// Serving stops when the server is shut down, otherwise shut it down
+	// because it failed to serve.
+	s.Lock()
+	stopped := s.stopped
+	s.Unlock()
 
-	return nil
+	if !stopped {
+		s.Shutdown()
+		return err
+	}
+
+	<-s.done
+	return s.err
+}
+
+// Stop the server and wait until it has shut down, returning any error that
+// occurred shutting down.
+func (s *Server) Stop() error {
+	s.Lock()
+	stop := s.stop
+	s.Unlock()
+
+	if stop == nil {
+		return s.Shutdown()
+	}
+
+	stop()
+	<-s.done
+	return s.err
+}
+
+// DialPeersWith sets the dialer that connects to peers, e.g. over an in-memory
+// network when several replicas are run in one process. Errors of the dialer
+// that are not temporary fail the connection immediately rather than being
+// retried until the timeout. Must be called before Run.
+func (s *Server) DialPeersWith(dialer Dialer) {
+	s.dialer = dialer
+}
+
+// View returns the latest version of every key in the store of the replica.
+func (s *Server) View() map[string]Version {
+	return s.store.View()
+}
+
+// isStopped returns true once the server has been shut down, so that tasks
+// scheduled periodically stop rescheduling themselves.
+func (s *Server) isStopped() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.stopped
 }
 
-// Uptime sets a fixed amount of time to keep the server up for, shutting it
-// down when the duration has passed and exiting gracefully.
+// runContext returns the context of the run, which is canceled on shutdown,
+// so that background work stops with the server.
+func (s *Server) runContext() context.Context {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// Uptime sets a fixed amount of time to keep the server up for once it is
+// run, shutting it down gracefully when the duration has passed.
 func (s *Server) Uptime(d time.Duration) {
-	time.AfterFunc(d, func() {
-		defer os.Exit(0)
+	s.uptime = d
+}
 
-		info("shutting down server after %s uptime", d)
-		s.Shutdown()
-	})
+// ShutdownTimeout sets the time the server waits for in-flight RPCs and
+// anti-entropy sessions to finish when it is shut down, after which they are
+// abandoned so that the logs, snapshot and metrics can still be written.
+func (s *Server) ShutdownTimeout(d time.Duration) {
+	s.grace = d
 }
 
 // Visibility opens the visibility logger at the specified path.
@@ -108,6 +358,12 @@ func (s *Server) Visibility(path string) (err error) {
 	return err
 }
 
+// BanditLog opens the logger that streams the bandit history to the path.
+func (s *Server) BanditLog(path string) (err error) {
+	s.banditlog, err = NewBanditLogger(path)
+	return err
+}
+
 // Measure the Honu server activity on shutdown. Pass in the paths to write
 // stats and history to on shutdown. If empty strings, they will be ignored.
 func (s *Server) Measure(stats, history string) {
@@ -115,44 +371,113 @@ func (s *Server) Measure(stats, history string) {
 	s.history = history
 }
 
+// Reward sets the function used to reward peers selected for anti-entropy.
+func (s *Server) Reward(fn RewardFunction) {
+	s.reward = fn
+}
+
 // Replicate the Honu server using anti-entropy.
-func (s *Server) Replicate(peers []string, delay time.Duration, strategy string, epsilon float64) error {
+func (s *Server) Replicate(peers []string, delay time.Duration, strategy string, opts BanditOptions) (err error) {
 	// Store the peers and delay on the server
 	s.peers = peers
 	s.delay = delay
 
 	// Create the peer selection strategy
-	strategy = strings.ToLower(strategy)
-	switch strategy {
-	case "uniform":
-		s.bandit = new(Uniform)
-	case "epsilon":
-		s.bandit = &EpsilonGreedy{Epsilon: epsilon}
-	case "annealing":
-		s.bandit = new(AnnealingEpsilonGreedy)
-	default:
-		return fmt.Errorf("no peer selection bandit strategy named  %s", strategy)
+	if s.bandit, err = NewBandit(strategy, opts); err != nil {
+		return err
 	}
 
 	// Initialize the bandit with the number of cases
 	s.bandit.Init(len(s.peers))
 
-	// Create the sync stats objects for each peer
+	// Create the sync stats objects and add the member for each peer
 	s.syncs = make(map[string]*SyncStats)
 	for _, peer := range peers {
-		s.syncs[peer] = new(SyncStats)
+		s.syncs[peer] = newSyncStats()
+		s.members.Add(peer)
 	}
 
-	// Schedule the anti-entropy delay
-	time.AfterFunc(s.delay, s.AntiEntropy)
-
 	// Give notice and return no error
 	info("replicating to %d peers with anti-entropy interval %s", len(peers), delay)
 	return nil
 }
 
-// Shutdown the Huno server, printing metrics.
+// Shutdown the Huno server in order: leave the cluster, stop accepting RPCs
+// and wait for the ones in flight, stop scheduling anti-entropy and wait for
+// the session in flight, then flush the logs and write the snapshot and
+// metrics. Waiting for in-flight work is bounded by the shutdown timeout; an
+// error is returned if it expired, but the server is still shut down.
 func (s *Server) Shutdown() error {
+	s.Lock()
+	if s.stopped {
+		s.Unlock()
+		<-s.done
+		return s.err
+	}
+
+	s.stopped = true
+	if s.entropy != nil {
+		s.entropy.Stop()
+	}
+	srv := s.srv
+	s.Unlock()
+	defer close(s.done)
+
+	deadline := time.Now().Add(s.grace)
+	var expired []string
+
+	// Announce that we're leaving the cluster so peers stop synchronizing
+	// with the replica while it drains.
+	if s.bandit != nil {
+		if err := s.leaveCluster(); err != nil {
+			warne(err)
+		} else {
+			info("announced departure from the cluster")
+		}
+	}
+
+	// Stop accepting RPCs and wait for the ones being handled
+	if srv != nil && !stopGracefully(srv, time.Until(deadline)) {
+		expired = append(expired, "rpcs")
+	}
+
+	// Wait for the in-flight anti-entropy session, no more are scheduled,
+	// then cancel the requests of the session if it hasn't finished.
+	if !waitTimeout(&s.sessions, time.Until(deadline)) {
+		expired = append(expired, "anti-entropy")
+	}
+
+	if s.stop != nil {
+		s.stop()
+	}
+
+	// Checkpoint what the bandit has learned
+	if err := s.saveCheckpoint(); err != nil {
+		warne(err)
+	}
+
+	// Stop logging the scheduled partitions
+	if s.faults != nil {
+		s.faults.Stop()
+	}
+
+	// Close the connections used to push writes eagerly
+	if s.handoff != nil {
+		s.handoff.Close()
+	}
+
+	// Close the connections used to repair reads
+	if s.repair != nil {
+		s.repair.Close()
+	}
+
+	// Flush the buffered visibility messages, later writes are not logged
+	if s.visibility != nil {
+		if err := s.visibility.Close(); err != nil {
+			warne(err)
+		}
+	}
+
 	// Save the version history snapshot
 	if s.history != "" {
 		if err := s.store.Snapshot(s.history); err != nil {
@@ -163,6 +488,18 @@ func (s *Server) Shutdown() error {
 
 	}
 
+	// Report the metrics of the last partial interval
+	if s.report != nil {
+		if err := s.report.reporter.Stop(); err != nil {
+			warne(err)
+		}
+	}
+
+	// Stop serving live metrics
+	if s.exporter != nil {
+		s.exporter.Close()
+	}
+
 	// Save the results stats to disk for analysis
 	if err := s.Metrics(s.stats); err != nil {
 		warn(err.Error())
@@ -172,6 +509,31 @@ func (s *Server) Shutdown() error {
 		}
 	}
 
+	// Flush the bandit history, no more rewards are logged once it's removed
+	s.Lock()
+	banditlog := s.banditlog
+	s.banditlog = nil
+	s.Unlock()
+
+	if banditlog != nil {
+		if err := banditlog.Close(); err != nil {
+			warne(err)
+		}
+	}
+
+	// Flush the spans that have ended, later spans are not recorded
+	if s.tracer != nil {
+		if err := s.tracer.Close(); err != nil {
+			warne(err)
+		}
+	}
+
+	if len(expired) > 0 {
+		s.err = fmt.Errorf("shutdown timeout of %s expired waiting for %s", s.grace, strings.Join(expired, " and "))
+		return s.err
+	}
+
+	status("honu has gracefully shutdown")
 	return nil
 }
 
@@ -182,14 +544,20 @@ func (s *Server) Shutdown() error {
 // GetValue implements the RPC for a get request from a client.
 func (s *Server) GetValue(ctx context.Context, in *pb.GetRequest) (*pb.GetReply, error) {
 	// Keep tracks of metrics with enter and exit
-	s.enter("read")
-	defer s.exit()
+	start := s.enter("read")
+	defer s.exit("read", start)
 
 	reply := new(pb.GetReply)
 	reply.Key = in.Key
 
+	_, span := s.tracer.Start(ctx, "store.Get")
+	span.SetAttribute("key", in.Key)
+	defer span.Finish()
+
 	var err error
 	reply.Value, reply.Version, err = s.store.Get(in.Key)
+	span.SetAttribute("version", reply.Version)
+	span.SetError(err)
 	if err != nil {
 		warn(err.Error())
 		reply.Success = false
@@ -199,20 +567,32 @@ func (s *Server) GetValue(ctx context.Context, in *pb.GetRequest) (*pb.GetReply,
 		debug("get key %s returns version %s", reply.Key, reply.Version)
 	}
 
+	// Repair the key with the peers if requested, even if it was not found
+	if s.repair != nil {
+		go s.repair.Read(in.Key)
+	}
+
 	return reply, nil
 }
 
 // PutValue implements the RPC for a put request from a client.
 func (s *Server) PutValue(ctx context.Context, in *pb.PutRequest) (*pb.PutReply, error) {
 	// Keep tracks of metrics with enter and exit
-	s.enter("write")
-	defer s.exit()
+	start := s.enter("write")
+	defer s.exit("write", start)
 
 	reply := new(pb.PutReply)
 	reply.Key = in.Key
 
+	// The span of the store operation is propagated with the entry to peers
+	_, span := s.tracer.Start(ctx, "store.Put")
+	span.SetAttribute("key", in.Key)
+	defer span.Finish()
+
 	var err error
-	reply.Version, err = s.store.Put(in.Key, in.Value, in.TrackVisibility)
+	reply.Version, err = s.store.Put(in.Key, in.Value, in.TrackVisibility, span.Context().String())
+	span.SetAttribute("version", reply.Version)
+	span.SetError(err)
 	if err != nil {
 		warn(err.Error())
 		reply.Success = false
@@ -220,12 +600,17 @@ func (s *Server) PutValue(ctx context.Context, in *pb.PutRequest) (*pb.PutReply,
 	} else {
 		reply.Success = true
 		debug("put key %s to version %s", reply.Key, reply.Version)
+
+		// Push the write to the peers if eager
+		if s.handoff != nil {
+			go s.handoff.Write(in.Key)
+		}
 	}
 
 	// Track visibility if requested
 	if err == nil && in.TrackVisibility {
 		if s.visibility != nil {
-			s.visibility.Log(in.Key, reply.Version)
+			s.visibility.Log(in.Key, reply.Version, span.Context().TraceID)
 			if err := s.visibility.Error(); err != nil {
 				warne(err)
 				reply.Error = err.Error()
@@ -247,6 +632,8 @@ func (s *Server) PutValue(ctx context.Context, in *pb.PutRequest) (*pb.PutReply,
 // so if the path is an empty string, the metrics can be reported to the log
 // without being saved to disk.
 func (s *Server) Metrics(path string) error {
+	nkeys := s.nkeys()
+
 	s.Lock()
 	defer s.Unlock()
 
@@ -273,7 +660,7 @@ func (s *Server) Metrics(path string) error {
 
 	status(
 		"stored %d items after %d successful synchronizations",
-		s.store.Length(), syncs,
+		nkeys, syncs,
 	)
 
 	// Compose the metrics to write to the given path.
@@ -287,12 +674,43 @@ func (s *Server) Metrics(path string) error {
 		data["reads"] = s.reads
 		data["writes"] = s.writes
 		data["throughput"] = throughput
+		data["latency"] = s.latency.Serialize()
 		data["store"] = s.stype
-		data["nkeys"] = s.store.Length()
+		data["nkeys"] = nkeys
 		data["syncs"] = s.syncs.Serialize()
-		data["bandit"] = s.bandit.Serialize()
 		data["peers"] = s.peers
 		data["host"] = s.addr
+		data["pid"] = s.members.local.PID
+		data["cluster"] = s.cluster
+		data["members"] = s.members.Serialize()
+
+		if s.bandit != nil {
+			data["bandit"] = s.bandit.Serialize()
+		}
+
+		if s.reward != nil {
+			data["reward"] = s.reward.Serialize()
+		}
+
+		if s.scheduler != nil {
+			data["scheduler"] = s.scheduler.Serialize()
+		}
+
+		if s.detector != nil {
+			data["detector"] = s.detector.Serialize()
+		}
+
+		if s.handoff != nil {
+			data["handoff"] = s.handoff.Serialize()
+		}
+
+		if s.repair != nil {
+			data["repair"] = s.repair.Serialize()
+		}
+
+		if s.faults != nil {
+			data["faults"] = s.faults.Serialize()
+		}
 
 		// Now write that data to disk
 		if err := appendJSON(path, data); err != nil {
@@ -304,14 +722,25 @@ func (s *Server) Metrics(path string) error {
 	return nil
 }
 
+// nkeys returns the number of keys in the store under its read lock. Must not
+// be called under the server lock, since gossip handlers that hold the store
+// lock take the server lock to merge the membership.
+func (s *Server) nkeys() int {
+	s.store.RLock()
+	defer s.store.RUnlock()
+	return s.store.Length()
+}
+
 // enter is called when an RPC method is started, it updates the count of the
 // number of messages as well as tracks the start time of the steady state.
-func (s *Server) enter(method string) {
+// Returns the time the method was started.
+func (s *Server) enter(method string) time.Time {
 	s.Lock()
 	defer s.Unlock()
 
+	now := time.Now()
 	if s.started.IsZero() {
-		s.started = time.Now()
+		s.started = now
 	}
 
 	switch method {
@@ -320,13 +749,27 @@ func (s *Server) enter(method string) {
 	case "write":
 		s.writes++
 	}
+
+	return now
 }
 
 // exit is called when an RPC method is complete, it updates the end time of
-// the steady state to measure the amount of throughput on the server.
-func (s *Server) exit() {
+// the steady state to measure the amount of throughput on the server and
+// records the latency of the method.
+func (s *Server) exit(method string, start time.Time) {
 	s.Lock()
-	defer s.Unlock()
-
 	s.finished = time.Now()
+	s.Unlock()
+
+	latency := time.Since(start)
+	s.latency[method].Update(latency)
+
+	if s.report != nil {
+		switch method {
+		case "read":
+			s.report.reads.Update(latency)
+		case "write":
+			s.report.writes.Update(latency)
+		}
+	}
 }
@@ -3,6 +3,7 @@ package honu
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 //===========================================================================
@@ -42,15 +43,17 @@ type Locker interface {
 // Store is an interface for multiple in-memory storage types under the hood.
 type Store interface {
 	Locker
-	Init(pid uint64)                                                                // Initialize the store
-	Get(key string) (value []byte, version string, err error)                       // Get a value and version for a given key
-	GetEntry(key string) *Entry                                                     // Get the entire entry without a lock
-	Put(key string, value []byte, trackVisibility bool) (version string, err error) // Put a value for a given key and get associated version
-	PutEntry(key string, entry *Entry) (modified bool)                              // Put the entry without modifying the version
-	View() map[string]Version                                                       // Returns a map containing the latest version of all keys
-	Update(key string, version *Version)                                            // Update the version scalar from a remote source
-	Snapshot(path string) error                                                     // Write a snapshot of the version history to disk
-	Length() int                                                                    // Returns the number of items in the store (number of keys)
+	Init(pid uint64)                                                                              // Initialize the store
+	Get(key string) (value []byte, version string, err error)                                     // Get a value and version for a given key
+	GetEntry(key string) *Entry                                                                   // Get the entire entry without a lock
+	Put(key string, value []byte, trackVisibility bool, trace string) (version string, err error) // Put a value for a given key and get associated version
+	PutEntry(key string, entry *Entry) (modified bool)                                            // Put the entry without modifying the version
+	View() map[string]Version                                                                     // Returns a map containing the latest version of all keys
+	Entries() map[string]*Entry                                                                   // Returns a consistent copy of the latest entry of all keys
+	Update(key string, version *Version)                                                          // Update the version scalar from a remote source
+	Snapshot(path string) error                                                                   // Write a snapshot of the version history to disk
+	Length() int                                                                                  // Returns the number of items in the store (number of keys)
+	Versions() uint64                                                                             // Returns the number of versions in the version history
 
 }
 
@@ -118,11 +121,12 @@ func (s *LinearizableStore) GetEntry(key string) *Entry {
 // Put a value into the namespace, incrementing the version across all
 // objects. This operation creates an entry whose parent is the last written
 // version of any object. Put also stores all versions and associated entries,
-// maintaining a complete version history.
+// maintaining a complete version history. The trace is the traceparent of the
+// write, if it is traced, and is propagated with the entry.
 //
 // This operation locks the entire store, waiting for all read locks to be
 // released and not allowing any other read or write locks until complete.
-func (s *LinearizableStore) Put(key string, value []byte, trackVisibility bool) (string, error) {
+func (s *LinearizableStore) Put(key string, value []byte, trackVisibility bool, trace string) (string, error) {
 	s.Lock()
 	defer s.Unlock()
 
@@ -137,6 +141,7 @@ func (s *LinearizableStore) Put(key string, value []byte, trackVisibility bool)
 		Parent:          s.lastWrite,
 		Value:           value,
 		TrackVisibility: trackVisibility,
+		Trace:           trace,
 	}
 
 	// Update the namespace, versions, and last write
@@ -178,6 +183,7 @@ func (s *LinearizableStore) PutEntry(key string, entry *Entry) bool {
 	current.Parent = entry.Parent
 	current.Value = entry.Value
 	current.TrackVisibility = entry.TrackVisibility
+	current.Trace = entry.Trace
 
 	// Update the namespace, versions, and last write
 	s.namespace[key] = current
@@ -186,10 +192,15 @@ func (s *LinearizableStore) PutEntry(key string, entry *Entry) bool {
 	return true
 }
 
-// Update the current version counter with the global value.
+// Update the current version counter with the global value. Concurrent pull
+// requests update the counter under the read lock, so it is compared and
+// swapped atomically.
 func (s *LinearizableStore) Update(key string, version *Version) {
-	if version.Scalar > s.current {
-		s.current = version.Scalar
+	for {
+		current := atomic.LoadUint64(&s.current)
+		if version.Scalar <= current || atomic.CompareAndSwapUint64(&s.current, current, version.Scalar) {
+			return
+		}
 	}
 }
 
@@ -206,10 +217,24 @@ func (s *LinearizableStore) View() map[string]Version {
 	return view
 }
 
+// Entries returns a copy of the latest entry of every key in the namespace.
+// The copy is consistent since all writes lock the entire store.
+func (s *LinearizableStore) Entries() map[string]*Entry {
+	s.RLock()
+	defer s.RUnlock()
+
+	entries := make(map[string]*Entry, len(s.namespace))
+	for key, entry := range s.namespace {
+		entries[key] = entry.copy()
+	}
+
+	return entries
+}
+
 // Snapshot the current version history to disk, writing the version data to
 // the specified path. Returns any I/O errors if snapshotting is unsuccessful.
 func (s *LinearizableStore) Snapshot(path string) error {
-	return fmt.Errorf("version history snapshot not implemented yet")
+	return s.history.Snapshot(path)
 }
 
 // Length returns the number of items in the Store, namely the number of keys
@@ -218,6 +243,11 @@ func (s *LinearizableStore) Length() int {
 	return len(s.namespace)
 }
 
+// Versions returns the number of versions in the version history.
+func (s *LinearizableStore) Versions() uint64 {
+	return s.history.Length()
+}
+
 //===========================================================================
 // Storage wtih Sequential Consistency
 //===========================================================================
@@ -330,8 +360,9 @@ func (s *SequentialStore) make(key string) *Entry {
 }
 
 // Put a value into the namespace and increment the version. Returns the
-// version for the given key and any error that might occur.
-func (s *SequentialStore) Put(key string, value []byte, trackVisibility bool) (string, error) {
+// version for the given key and any error that might occur. The trace is the
+// traceparent of the write, if it is traced, and is propagated with the entry.
+func (s *SequentialStore) Put(key string, value []byte, trackVisibility bool, trace string) (string, error) {
 	// Attempt to get the write-locked version from the store
 	entry := s.get(key, true)
 
@@ -353,6 +384,7 @@ func (s *SequentialStore) Put(key string, value []byte, trackVisibility bool) (s
 	// Update the value
 	entry.Value = value
 	entry.TrackVisibility = trackVisibility
+	entry.Trace = trace
 
 	// Store the version in the version history and return it
 	s.history.Append(entry.Key, entry.Parent, entry.Version)
@@ -391,17 +423,21 @@ func (s *SequentialStore) PutEntry(key string, entry *Entry) bool {
 	current.Parent = entry.Parent
 	current.Value = entry.Value
 	current.TrackVisibility = entry.TrackVisibility
+	current.Trace = entry.Trace
 
 	// Store the version in the version history and return true.
 	s.history.Append(current.Key, current.Parent, current.Version)
 	return true
 }
 
-// Update the current version counter with the global value.
+// Update the current version counter with the global value. Keys that are
+// not in the namespace are not created, since their counter is set when the
+// entry is put; creating them would require a write lock on the store, which
+// may be read locked by the caller.
 func (s *SequentialStore) Update(key string, version *Version) {
 	entry := s.get(key, true)
 	if entry == nil {
-		entry = s.make(key)
+		return
 	}
 
 	defer entry.Unlock()
@@ -424,10 +460,27 @@ func (s *SequentialStore) View() map[string]Version {
 	return view
 }
 
+// Entries returns a copy of the latest entry of every key in the namespace.
+// Each entry is read locked while it is copied, since keys are versioned
+// independently there is no ordering between the versions of different keys.
+func (s *SequentialStore) Entries() map[string]*Entry {
+	s.RLock()
+	defer s.RUnlock()
+
+	entries := make(map[string]*Entry, len(s.namespace))
+	for key, entry := range s.namespace {
+		entry.RLock()
+		entries[key] = entry.copy()
+		entry.RUnlock()
+	}
+
+	return entries
+}
+
 // Snapshot the current version history to disk, writing the version data to
 // the specified path. Returns any I/O errors if snapshotting is unsuccessful.
 func (s *SequentialStore) Snapshot(path string) error {
-	return fmt.Errorf("version history snapshot not implemented yet")
+	return s.history.Snapshot(path)
 }
 
 // Length returns the number of items in the Store, namely the number of keys
@@ -435,3 +488,8 @@ func (s *SequentialStore) Snapshot(path string) error {
 func (s *SequentialStore) Length() int {
 	return len(s.namespace)
 }
+
+// Versions returns the number of versions in the version history.
+func (s *SequentialStore) Versions() uint64 {
+	return s.history.Length()
+}
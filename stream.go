@@ -0,0 +1,479 @@
+package honu
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/bbengfort/honu/rpc"
+	"golang.org/x/net/context"
+)
+
+// ErrStreamClosed is returned when a peer ends a streaming synchronization
+// before it is done.
+var ErrStreamClosed = errors.New("sync stream closed before synchronization was done")
+
+// Streaming sets anti-entropy to synchronize with a bidirectional stream of
+// version digests and entries in batches within the message limit, rather
+// than a single pull and push, so that namespaces that do not fit in a
+// single message can be synchronized. Streaming sessions also send the
+// entries of keys that the initiator does not have at all. Peers that do
+// not implement streaming are synchronized with unary pull and push.
+func (s *Server) Streaming(enabled bool) {
+	s.streaming = enabled
+}
+
+//===========================================================================
+// Streaming Anti-Entropy
+//===========================================================================
+
+// syncStream performs a bilateral synchronization with the peer over a
+// bidirectional stream. The digest of the view is streamed in batches while
+// the entries and requested versions streamed back by the peer are received
+// concurrently, then the requested entries are streamed to the peer. Flow
+// control is provided by the stream, which blocks senders until the receiver
+// has consumed earlier batches.
+func (s *Server) syncStream(ctx context.Context, client pb.GossipClient, vector map[string]Version, writes uint64, session *Synchronization, metrics *SyncStats) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := client.Sync(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Receive from the peer until it is done, collecting the versions it
+	// requests to be pushed. The session is not modified by the sender until
+	// the receiver is done.
+	var received int
+	pull := make(map[string]*pb.Version)
+	errc := make(chan error, 1)
+	start := time.Now()
+
+	go func() {
+		errc <- s.receiveSync(stream, vector, pull, session, metrics.bandwidth, &received)
+	}()
+
+	// Stream the digest of the view, piggybacking our view of the membership
+	// and the codec that entries should be compressed with on the first batch
+	batch := newSyncBatch(stream, s.limit, metrics.bandwidth)
+	batch.msg.Members = s.members.topb()
+	if s.codec != nil {
+		batch.msg.Encoding = s.codec.Name()
+	}
+
+	for key, version := range vector {
+		if err = batch.version(key, version.topb()); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = batch.done()
+	}
+
+	if err != nil {
+		// If the peer ended the stream, its status is returned by the receiver
+		if err != io.EOF {
+			cancel()
+		}
+
+		if rerr := <-errc; rerr != nil {
+			err = rerr
+		}
+		return err
+	}
+
+	if err = <-errc; err != nil {
+		return err
+	}
+
+	pullLatency := time.Since(start)
+	session.Pulled = true
+	session.PullLatency += pullLatency
+	session.Bytes += uint64(received)
+	s.updateSync(func() {
+		metrics.Update(pullLatency, "pull")
+		metrics.LastLatency = pullLatency
+		metrics.LastSync = time.Now()
+		metrics.writes = writes
+		metrics.Pulls++
+	})
+
+	// Stream the requested entries back to the peer (bilateral) while the
+	// bandwidth budget allows it, then close the stream.
+	var pushed, stale uint64
+	codec := s.pushCodec(session.Peer)
+	digest := batch.sent
+	start = time.Now()
+
+	if len(pull) > 0 {
+		debug("streaming %d versions back to %s", len(pull), session.Peer)
+	}
+
+	for key, pbvers := range pull {
+		if !metrics.bandwidth.Available() {
+			s.updateSync(func() { metrics.Throttled++ })
+			debug("bandwidth budget of %s exhausted during push", session.Peer)
+			break
+		}
+
+		s.store.RLock()
+		entry := s.store.GetEntry(key).copy()
+		s.store.RUnlock()
+
+		if err = batch.entry(key, encodeEntry(entry, codec)); err != nil {
+			return err
+		}
+
+		remote := Version{}
+		remote.frompb(pbvers)
+
+		pushed++
+		stale += staleness(entry.Version, remote)
+	}
+
+	if err = batch.flush(); err != nil {
+		return err
+	}
+
+	if err = stream.CloseSend(); err != nil {
+		return err
+	}
+
+	// Wait for the peer to end the stream once it has received the entries
+	if _, err = stream.Recv(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected message after sync stream was done")
+		}
+		return err
+	}
+
+	if pushed > 0 {
+		pushLatency := time.Since(start)
+		session.Pushed = true
+		session.PushLatency += pushLatency
+		session.PushItems += pushed
+		session.Staleness += stale
+		session.Bytes += uint64(batch.sent - digest)
+		s.updateSync(func() {
+			metrics.Update(pushLatency, "push")
+			metrics.Pushes++
+		})
+	}
+
+	// Log anti-entropy success and metrics
+	items := session.Items()
+	s.updateSync(func() {
+		metrics.Sent += uint64(batch.sent)
+		metrics.Received += uint64(received)
+		metrics.Syncs++
+		metrics.Versions += items
+	})
+	infokv("synchronized by stream", "items", items, "peer", session.Peer, "latency", session.Latency())
+	return nil
+}
+
+// receiveSync puts the entries streamed by the peer and collects the versions
+// it requests until the peer is done, counting the bytes received.
+func (s *Server) receiveSync(stream pb.Gossip_SyncClient, vector map[string]Version, pull map[string]*pb.Version, session *Synchronization, budget *bandwidth, received *int) error {
+	for first := true; ; first = false {
+		msg, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return ErrStreamClosed
+			}
+			return err
+		}
+
+		n := proto.Size(msg)
+		*received += n
+		budget.Consume(n)
+
+		// The first message requests the encoding of the entries pushed back
+		if first {
+			s.acknowledged(session.Peer, msg.Encoding)
+		}
+
+		// Merge the remote view of the membership
+		if len(msg.Members) > 0 {
+			s.membershipChanged(s.members.Merge(msg.Members))
+		}
+
+		for key, pbentry := range msg.Entries {
+			if entry, ok := s.accept(stream.Context(), key, pbentry); ok {
+				session.PullItems++
+				session.Staleness += staleness(entry.Version, vector[key])
+			}
+		}
+
+		for key, pbvers := range msg.Versions {
+			pull[key] = pbvers
+		}
+
+		if msg.Done {
+			return nil
+		}
+	}
+}
+
+// accept decodes the entry and puts it into the store if it is later than
+// the local version, tracking its visibility if requested. If the write is
+// traced, a span continuing its trace is recorded when it becomes visible,
+// linked to the span of the synchronization in the context, and propagated
+// with the entry to the next hop. Returns the entry and true if the store
+// was modified.
+func (s *Server) accept(ctx context.Context, key string, pbentry *pb.Entry) (*Entry, bool) {
+	entry, err := decodeEntry(pbentry)
+	if err != nil {
+		warne(err)
+		return nil, false
+	}
+
+	var span *Span
+	if parent := ParseSpanContext(entry.Trace); parent.IsValid() && s.tracer != nil {
+		span = s.tracer.StartFrom(parent, "store.PutEntry")
+		span.Link(spanFromContext(ctx))
+		span.SetAttribute("key", key)
+		span.SetAttribute("version", entry.Version.String())
+		entry.Trace = span.Context().String()
+	}
+
+	// The span is only recorded if the entry becomes visible
+	if !s.store.PutEntry(key, entry) {
+		return entry, false
+	}
+	span.Finish()
+
+	// Track visibility if requested
+	if s.visibility != nil && entry.TrackVisibility {
+		s.visibility.Log(key, entry.Version.String(), ParseSpanContext(entry.Trace).TraceID)
+		if err := s.visibility.Error(); err != nil {
+			warne(err)
+		}
+	}
+
+	return entry, true
+}
+
+//===========================================================================
+// Server Streaming Gossip RPC methods
+//===========================================================================
+
+// Sync handles incoming streaming synchronizations. Each batch of the digest
+// is compared with the current view under a read lock, streaming back the
+// entries that are later than the remote and requesting the versions that
+// are later than the local. Once the digest is done, the entries of keys
+// that were missing from it are streamed, followed by a done message, and
+// the entries pushed by the remote are accepted until it closes the stream.
+func (s *Server) Sync(stream pb.Gossip_SyncServer) error {
+	var codec Codec
+	var batch *syncBatch
+	seen := make(map[string]struct{})
+
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// The first message carries the encoding and the remote membership
+		if batch == nil {
+			if codec, err = NewCodec(in.Encoding); err != nil {
+				debug("streaming uncompressed values: %s", err)
+			}
+			batch = newSyncBatch(stream, s.limit, nil)
+			if codec != nil {
+				batch.msg.Encoding = codec.Name()
+			}
+
+			if s.bandit != nil {
+				s.membershipChanged(s.members.Merge(in.Members))
+			}
+		}
+
+		// Accept entries pushed by the remote
+		for key, pbentry := range in.Entries {
+			s.accept(stream.Context(), key, pbentry)
+		}
+
+		// Compare the digest with the local view
+		if len(in.Versions) > 0 {
+			if err = s.compareDigest(in.Versions, seen, codec, batch); err != nil {
+				return err
+			}
+		}
+
+		if !in.Done {
+			continue
+		}
+
+		// Stream the entries of the keys that the remote does not have
+		for key := range s.store.View() {
+			if _, ok := seen[key]; ok {
+				continue
+			}
+
+			s.store.RLock()
+			entry := s.store.GetEntry(key).copy()
+			s.store.RUnlock()
+
+			if err = batch.entry(key, encodeEntry(entry, codec)); err != nil {
+				return err
+			}
+		}
+
+		seen = make(map[string]struct{})
+		if s.bandit != nil {
+			batch.msg.Members = s.members.topb()
+		}
+
+		if err = batch.done(); err != nil {
+			return err
+		}
+	}
+}
+
+// compareDigest compares a batch of the remote digest with the local view,
+// adding later local entries and requests for later remote versions to the
+// batch. The store is read locked while comparing, but not while sending.
+func (s *Server) compareDigest(digest map[string]*pb.Version, seen map[string]struct{}, codec Codec, batch *syncBatch) error {
+	entries := make(map[string]*pb.Entry)
+	requests := make(map[string]*pb.Version)
+
+	s.store.RLock()
+	for key, pbvers := range digest {
+		seen[key] = struct{}{}
+
+		version := new(Version)
+		version.frompb(pbvers)
+		entry := s.store.GetEntry(key)
+
+		if entry == nil || version.Greater(entry.Version) {
+			// Remote is greater than our local, request it to be pushed.
+			vers := new(pb.Version)
+			if entry != nil {
+				vers = entry.Version.topb()
+			}
+
+			s.store.Update(key, version)
+			requests[key] = vers
+		} else if entry.Version.Greater(version) {
+			// Local is greater than the remote, send it on.
+			entries[key] = encodeEntry(entry, codec)
+		}
+	}
+	s.store.RUnlock()
+
+	for key, vers := range requests {
+		if err := batch.version(key, vers); err != nil {
+			return err
+		}
+	}
+
+	for key, pbentry := range entries {
+		if err := batch.entry(key, pbentry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//===========================================================================
+// Sync Message Batches
+//===========================================================================
+
+// syncSender is implemented by both the client and server sides of a sync
+// stream.
+type syncSender interface {
+	Send(*pb.SyncMessage) error
+}
+
+// syncBatch accumulates versions and entries into sync messages, sending a
+// message whenever adding to it would exceed the message limit. Each message
+// contains at least one version or entry.
+type syncBatch struct {
+	stream syncSender      // The stream the messages are sent on
+	limit  uint64          // The maximum size of a message (unlimited if zero)
+	budget *bandwidth      // Consumed by the bytes sent (nil if unlimited)
+	msg    *pb.SyncMessage // The message being accumulated
+	size   uint64          // The approximate size of the message
+	sent   int             // The number of bytes sent on the stream
+}
+
+// newSyncBatch creates a batch that sends messages on the stream.
+func newSyncBatch(stream syncSender, limit uint64, budget *bandwidth) *syncBatch {
+	batch := &syncBatch{stream: stream, limit: limit, budget: budget}
+	batch.reset()
+	return batch
+}
+
+// version adds a version to the batch.
+func (b *syncBatch) version(key string, version *pb.Version) error {
+	n := uint64(len(key) + proto.Size(version) + 8)
+	if err := b.reserve(n); err != nil {
+		return err
+	}
+
+	b.msg.Versions[key] = version
+	b.size += n
+	return nil
+}
+
+// entry adds an entry to the batch.
+func (b *syncBatch) entry(key string, entry *pb.Entry) error {
+	n := entrySize(key, entry)
+	if err := b.reserve(n); err != nil {
+		return err
+	}
+
+	b.msg.Entries[key] = entry
+	b.size += n
+	return nil
+}
+
+// reserve sends the message if adding n bytes to it would exceed the limit.
+func (b *syncBatch) reserve(n uint64) error {
+	if b.limit == 0 || b.size == 0 || b.size+n <= b.limit {
+		return nil
+	}
+	return b.flush()
+}
+
+// flush sends the message if it is not empty.
+func (b *syncBatch) flush() error {
+	if len(b.msg.Versions) == 0 && len(b.msg.Entries) == 0 && !b.msg.Done {
+		return nil
+	}
+
+	if err := b.stream.Send(b.msg); err != nil {
+		return err
+	}
+
+	n := proto.Size(b.msg)
+	b.sent += n
+	b.budget.Consume(n)
+	b.reset()
+	return nil
+}
+
+// done sends the remainder of the batch marked as done.
+func (b *syncBatch) done() error {
+	b.msg.Done = true
+	return b.flush()
+}
+
+// reset the batch with an empty message.
+func (b *syncBatch) reset() {
+	b.msg = &pb.SyncMessage{
+		Versions: make(map[string]*pb.Version),
+		Entries:  make(map[string]*pb.Entry),
+	}
+	b.size = 0
+}
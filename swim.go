@@ -0,0 +1,282 @@
+package honu
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	pb "github.com/bbengfort/honu/rpc"
+)
+
+//===========================================================================
+// SWIM Failure Detection
+//===========================================================================
+
+// FailureDetector implements the SWIM failure detection protocol over the
+// Gossip service. Every protocol period a member is selected in randomized
+// round-robin order and probed directly. If it does not acknowledge the probe
+// within the timeout, k other members are asked to probe it indirectly. If no
+// acknowledgement is received, the member is suspected, and if the suspicion
+// is not refuted before the suspicion timeout the member is declared dead.
+// Membership updates are piggybacked on every probe and acknowledgement.
+type FailureDetector struct {
+	sync.Mutex
+	Interval     time.Duration // The protocol period between probes
+	Timeout      time.Duration // The time to wait for an acknowledgement
+	Indirect     int           // The number of members to request indirect probes from
+	Suspicion    time.Duration // The time a suspect has to refute before it is dead
+	Probes       uint64        // The number of probes sent by the detector
+	Acks         uint64        // The number of direct acknowledgements received
+	IndirectAcks uint64        // The number of indirect acknowledgements received
+	Suspicions   uint64        // The number of members that were suspected
+	Failures     uint64        // The number of members that were declared dead
+	server       *Server       // The server whose membership is being monitored
+	order        []string      // The round-robin order of members to probe
+}
+
+// DetectFailures configures the server to run the SWIM failure detector with
+// the specified protocol period, probe timeout, suspicion timeout and number
+// of indirect probes. The detector is started when the server is run.
+func (s *Server) DetectFailures(interval, timeout, suspicion time.Duration, indirect int) {
+	s.detector = &FailureDetector{
+		Interval:  interval,
+		Timeout:   timeout,
+		Indirect:  indirect,
+		Suspicion: suspicion,
+		server:    s,
+	}
+}
+
+// Run the failure detector, scheduling the first protocol period.
+func (d *FailureDetector) Run() {
+	time.AfterFunc(d.Interval, d.Probe)
+	info(
+		"detecting failures every %s with %d indirect probes and %s suspicion timeout",
+		d.Interval, d.Indirect, d.Suspicion,
+	)
+}
+
+// Probe the next member in the round-robin order directly and then indirectly
+// if required, suspecting the member if no acknowledgement is received.
+func (d *FailureDetector) Probe() {
+	// Stop probing once the server has been shut down
+	if d.server.isStopped() {
+		return
+	}
+
+	// Schedule the next protocol period
+	defer time.AfterFunc(d.Interval, d.Probe)
+
+	target := d.next()
+	if target == "" {
+		return
+	}
+
+	d.Lock()
+	d.Probes++
+	d.Unlock()
+
+	if d.ping(target) {
+		d.Lock()
+		d.Acks++
+		d.Unlock()
+		return
+	}
+
+	if d.indirect(target) {
+		d.Lock()
+		d.IndirectAcks++
+		d.Unlock()
+		return
+	}
+
+	// Neither direct nor indirect probes were acknowledged
+	members := d.server.members
+	change := members.Suspect(target)
+	if change == nil {
+		return
+	}
+
+	d.Lock()
+	d.Suspicions++
+	d.Unlock()
+
+	d.server.membershipChanged([]Member{*change})
+	info("suspect %s after unacknowledged probes", target)
+
+	// Declare the member dead if it does not refute the suspicion in time
+	incarnation := change.Incarnation
+	time.AfterFunc(d.Suspicion, func() {
+		if change := members.Confirm(target, incarnation); change != nil {
+			d.Lock()
+			d.Failures++
+			d.Unlock()
+
+			d.server.membershipChanged([]Member{*change})
+		}
+	})
+}
+
+// next returns the next member to probe, shuffling the members into a new
+// round-robin order when all members have been probed. Dead members are also
+// probed so that they can be detected when they are alive again.
+func (d *FailureDetector) next() string {
+	members := d.server.members
+	for {
+		if len(d.order) == 0 {
+			d.order = members.Filter(MemberAlive, MemberSuspect, MemberDead)
+			if len(d.order) == 0 {
+				return ""
+			}
+
+			for i := range d.order {
+				j := rand.Intn(i + 1)
+				d.order[i], d.order[j] = d.order[j], d.order[i]
+			}
+		}
+
+		target := d.order[0]
+		d.order = d.order[1:]
+
+		// Skip members that have left since the order was created
+		if member, ok := members.Get(target); ok && member.Status != MemberLeft {
+			return target
+		}
+	}
+}
+
+// ping the target directly, merging the membership piggybacked on the
+// acknowledgement. Returns true if the target acknowledged the probe.
+func (d *FailureDetector) ping(target string) bool {
+	var rep *pb.PingReply
+	req := &pb.PingRequest{Members: d.server.members.topb()}
+
+	err := d.call(target, d.Timeout, func(ctx context.Context, client pb.GossipClient) (err error) {
+		rep, err = client.Ping(ctx, req)
+		return err
+	})
+
+	if err != nil {
+		debug("could not ping %s: %s", target, err)
+		return false
+	}
+
+	d.server.membershipChanged(d.server.members.Merge(rep.Members))
+	return rep.Ack
+}
+
+// indirect requests that up to k alive members probe the target on behalf of
+// the local replica. Returns true if any of the members received an ack.
+func (d *FailureDetector) indirect(target string) bool {
+	// Select k random alive members other than the target
+	alive := d.server.members.Alive()
+	relays := make([]string, 0, d.Indirect)
+	for _, idx := range rand.Perm(len(alive)) {
+		relay := alive[idx]
+		if relay == target {
+			continue
+		}
+
+		relays = append(relays, relay)
+		if len(relays) >= d.Indirect {
+			break
+		}
+	}
+
+	if len(relays) == 0 {
+		return false
+	}
+
+	// Send the indirect probes in parallel and wait for all replies.
+	acks := make(chan bool, len(relays))
+	req := &pb.PingRequest{Target: target, Members: d.server.members.topb()}
+
+	for _, relay := range relays {
+		go func(relay string) {
+			var rep *pb.PingReply
+			err := d.call(relay, 2*d.Timeout, func(ctx context.Context, client pb.GossipClient) (err error) {
+				rep, err = client.PingReq(ctx, req)
+				return err
+			})
+
+			if err != nil {
+				debug("could not request %s to ping %s: %s", relay, target, err)
+				acks <- false
+				return
+			}
+
+			d.server.membershipChanged(d.server.members.Merge(rep.Members))
+			acks <- rep.Ack
+		}(relay)
+	}
+
+	acked := false
+	for range relays {
+		if <-acks {
+			acked = true
+		}
+	}
+	return acked
+}
+
+// call connects to the address and executes the rpc with the given timeout.
+func (d *FailureDetector) call(addr string, timeout time.Duration, rpc func(context.Context, pb.GossipClient) error) error {
+	conn, err := d.server.dial(context.Background(), addr, grpc.WithTimeout(timeout))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return rpc(ctx, pb.NewGossipClient(conn))
+}
+
+// Serialize the failure detector metrics to write to disk
+func (d *FailureDetector) Serialize() map[string]interface{} {
+	d.Lock()
+	defer d.Unlock()
+
+	data := make(map[string]interface{})
+	data["Interval"] = d.Interval.String()
+	data["Timeout"] = d.Timeout.String()
+	data["Indirect"] = d.Indirect
+	data["Suspicion"] = d.Suspicion.String()
+	data["Probes"] = d.Probes
+	data["Acks"] = d.Acks
+	data["IndirectAcks"] = d.IndirectAcks
+	data["Suspicions"] = d.Suspicions
+	data["Failures"] = d.Failures
+	return data
+}
+
+//===========================================================================
+// Server Failure Detection RPC methods
+//===========================================================================
+
+// Ping acknowledges a direct probe, exchanging views of the membership.
+func (s *Server) Ping(ctx context.Context, in *pb.PingRequest) (*pb.PingReply, error) {
+	reply := &pb.PingReply{Ack: true}
+	if s.bandit != nil {
+		s.membershipChanged(s.members.Merge(in.Members))
+		reply.Members = s.members.topb()
+	}
+	return reply, nil
+}
+
+// PingReq probes the target on behalf of the requesting replica, returning
+// an acknowledgement if the target acknowledged the probe.
+func (s *Server) PingReq(ctx context.Context, in *pb.PingRequest) (*pb.PingReply, error) {
+	if s.detector == nil || s.bandit == nil {
+		return &pb.PingReply{Ack: false}, nil
+	}
+
+	s.membershipChanged(s.members.Merge(in.Members))
+	return &pb.PingReply{
+		Ack:     s.detector.ping(in.Target),
+		Members: s.members.topb(),
+	}, nil
+}
@@ -0,0 +1,378 @@
+package honu
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceBufferSize describes the maximum number of async spans before the
+// caller will have to block.
+const TraceBufferSize = 10000
+
+// traceparentKey is the gRPC metadata key that propagates the span context
+// of the caller, in the W3C trace context format.
+const traceparentKey = "traceparent"
+
+// Trace records spans of the server to the JSON Lines file at the path. The
+// specified fraction of requests and anti-entropy sessions that are not part
+// of a trace already start a new trace. Must be called before Run.
+func (s *Server) Trace(path string, sample float64) (err error) {
+	if sample < 0 || sample > 1 {
+		return fmt.Errorf("trace sample must be in [0, 1], not %f", sample)
+	}
+
+	s.tracer, err = NewTracer(path, s.members.local.PID, sample)
+	return err
+}
+
+//===========================================================================
+// Tracer
+//===========================================================================
+
+// NewTracer creates a tracer that streams the spans that end to the JSON
+// Lines file at the path.
+func NewTracer(path string, pid uint64, sample float64) (*Tracer, error) {
+	out, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tracer{
+		pid:    pid,
+		sample: sample,
+		file:   out,
+		err:    nil,
+		spans:  make(chan *Span, TraceBufferSize),
+		done:   make(chan bool),
+	}
+
+	go t.flusher()
+	return t, nil
+}
+
+// Tracer follows writes from the replica they were made on through every
+// anti-entropy hop to the replicas they become visible on. Trace context is
+// propagated to peers in gRPC metadata and with every gossiped entry, so the
+// propagation tree of a write can be reconstructed offline from the spans of
+// every replica. Spans are written in a format similar to OTLP JSON, one
+// span per line, by an asynchronous writer so tracing doesn't block
+// requests. A nil tracer records nothing.
+type Tracer struct {
+	sync.Mutex
+	pid    uint64  // The process id of the replica recorded with every span
+	sample float64 // The fraction of new traces that are recorded
+	file   *os.File
+	err    error
+	spans  chan *Span
+	done   chan bool
+	closed bool
+}
+
+// Start a span that is a child of the span in the context, or the root of a
+// new trace if the sample allows it. The returned context carries the span
+// and propagates it to peers in the metadata of outgoing requests. If no
+// span is recorded, the context is returned unmodified with a nil span.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := spanFromContext(ctx)
+	if !parent.IsValid() && mrand.Float64() >= t.sample {
+		return ctx, nil
+	}
+
+	span := t.StartFrom(parent, name)
+	return contextWithSpan(ctx, span.Context()), span
+}
+
+// StartFrom starts a child span of the parent span context, or the root of
+// a new trace if the parent is not valid.
+func (t *Tracer) StartFrom(parent SpanContext, name string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	span := &Span{
+		SpanID:     newTraceID(8),
+		Name:       name,
+		Attributes: map[string]interface{}{"honu.pid": t.pid},
+		tracer:     t,
+		start:      time.Now(),
+	}
+
+	if parent.IsValid() {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		span.TraceID = newTraceID(16)
+	}
+
+	return span
+}
+
+// Close the tracer and wait until it's done writing all buffered spans.
+// Spans that end after the tracer is closed are not recorded.
+func (t *Tracer) Close() error {
+	t.Lock()
+	if t.closed {
+		t.Unlock()
+		return t.err
+	}
+	t.closed = true
+	close(t.spans)
+	t.Unlock()
+
+	<-t.done
+
+	if t.err != nil {
+		return t.err
+	}
+
+	if err := t.file.Sync(); err != nil {
+		return err
+	}
+
+	return t.file.Close()
+}
+
+// Error returns any issues the tracer had
+func (t *Tracer) Error() error {
+	return t.err
+}
+
+// export the span to the flusher unless the tracer is closed.
+func (t *Tracer) export(span *Span) {
+	t.Lock()
+	defer t.Unlock()
+
+	if !t.closed {
+		t.spans <- span
+	}
+}
+
+// routine that reads spans off the spans channel and writes them to disk.
+// The file is synced when the tracer is closed.
+func (t *Tracer) flusher() {
+	defer func() { t.done <- true }()
+
+	for span := range t.spans {
+		var data []byte
+
+		if data, t.err = json.Marshal(span); t.err != nil {
+			warne(t.err)
+			break
+		}
+
+		data = append(data, byte('\n'))
+		if _, t.err = t.file.Write(data); t.err != nil {
+			warne(t.err)
+			break
+		}
+	}
+
+	// Drain the remaining spans so that callers do not block
+	for range t.spans {
+	}
+}
+
+//===========================================================================
+// Spans
+//===========================================================================
+
+// Span is a timed operation of a trace, recorded when it ends. The methods
+// of a nil span do nothing, so spans that are not sampled can be used as if
+// they were.
+type Span struct {
+	sync.Mutex
+	TraceID      string                 `json:"traceId"`
+	SpanID       string                 `json:"spanId"`
+	ParentSpanID string                 `json:"parentSpanId,omitempty"`
+	Name         string                 `json:"name"`
+	Start        int64                  `json:"startTimeUnixNano"`
+	End          int64                  `json:"endTimeUnixNano"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Links        []SpanContext          `json:"links,omitempty"`
+	Status       *SpanStatus            `json:"status,omitempty"`
+	tracer       *Tracer
+	start        time.Time
+}
+
+// SpanStatus describes an error that occurred during the span.
+type SpanStatus struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Context returns the span context that identifies the span in its trace,
+// which is invalid for a nil span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.TraceID, SpanID: s.SpanID}
+}
+
+// SetAttribute records a key/value attribute of the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.Lock()
+	s.Attributes[key] = value
+	s.Unlock()
+}
+
+// Link the span to a span of another trace that caused it.
+func (s *Span) Link(ctx SpanContext) {
+	if s == nil || !ctx.IsValid() {
+		return
+	}
+
+	s.Lock()
+	s.Links = append(s.Links, ctx)
+	s.Unlock()
+}
+
+// SetError records that the span failed with the error, if it is not nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.Lock()
+	s.Status = &SpanStatus{Code: "ERROR", Message: err.Error()}
+	s.Unlock()
+}
+
+// Finish the span and record it.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+
+	s.Lock()
+	s.Start = s.start.UnixNano()
+	s.End = time.Now().UnixNano()
+	s.Unlock()
+
+	s.tracer.export(s)
+}
+
+//===========================================================================
+// Span Context Propagation
+//===========================================================================
+
+// SpanContext identifies a span in a trace and is propagated to peers in the
+// W3C traceparent format, both in gRPC metadata and with gossiped entries.
+type SpanContext struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+// ParseSpanContext parses a traceparent, returning an invalid span context
+// if it is malformed.
+func ParseSpanContext(traceparent string) SpanContext {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: parts[1], SpanID: parts[2]}
+}
+
+// IsValid returns true if the span context identifies a span.
+func (c SpanContext) IsValid() bool {
+	return c.TraceID != "" && c.SpanID != ""
+}
+
+// String returns the traceparent of a sampled span, or an empty string if
+// the span context is not valid.
+func (c SpanContext) String() string {
+	if !c.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", c.TraceID, c.SpanID)
+}
+
+type spanContextKey struct{}
+
+// contextWithSpan returns a context that carries the span context, which is
+// also set in the metadata of outgoing requests.
+func contextWithSpan(ctx context.Context, span SpanContext) context.Context {
+	ctx = context.WithValue(ctx, spanContextKey{}, span)
+	return metadata.NewContext(ctx, metadata.Pairs(traceparentKey, span.String()))
+}
+
+// spanFromContext returns the span context carried by the context, or the
+// span context of the caller in the metadata of an incoming request.
+func spanFromContext(ctx context.Context) SpanContext {
+	if span, ok := ctx.Value(spanContextKey{}).(SpanContext); ok {
+		return span
+	}
+
+	if md, ok := metadata.FromContext(ctx); ok {
+		if values := md[traceparentKey]; len(values) > 0 {
+			return ParseSpanContext(values[0])
+		}
+	}
+
+	return SpanContext{}
+}
+
+// newTraceID returns a random hex identifier of n bytes. Identifiers are not
+// generated with math/rand since replicas may share the same random seed.
+func newTraceID(n int) string {
+	id := make([]byte, n)
+	if _, err := rand.Read(id); err != nil {
+		warne(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+//===========================================================================
+// Tracing Interceptors
+//===========================================================================
+
+// UnaryInterceptor records a span for every unary request handled by the
+// server, continuing the trace of the caller if there is one.
+func (t *Tracer) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := t.Start(ctx, strings.TrimPrefix(info.FullMethod, "/"))
+	rep, err := handler(ctx, req)
+	span.SetError(err)
+	span.Finish()
+	return rep, err
+}
+
+// StreamInterceptor records a span for every stream handled by the server,
+// continuing the trace of the caller if there is one.
+func (t *Tracer) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := t.Start(ss.Context(), strings.TrimPrefix(info.FullMethod, "/"))
+	err := handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+	span.SetError(err)
+	span.Finish()
+	return err
+}
+
+// tracedStream is a server stream whose context carries the span of the
+// stream.
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream with its span.
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
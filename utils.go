@@ -5,6 +5,11 @@ import (
 	"math/rand"
 	"os"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 )
 
 //===========================================================================
@@ -67,3 +72,77 @@ func randomVowel() string {
 func randomConsonant() string {
 	return consonants[rand.Intn(len(consonants))]
 }
+
+//===========================================================================
+// Interceptor chaining
+//===========================================================================
+
+// chainUnary combines unary server interceptors into a single interceptor,
+// since only one can be registered. The first interceptor is the outermost.
+func chainUnary(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return chained(ctx, req)
+	}
+}
+
+// chainStream combines stream server interceptors into a single interceptor,
+// since only one can be registered. The first interceptor is the outermost.
+func chainStream(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, next)
+			}
+		}
+		return chained(srv, ss)
+	}
+}
+
+//===========================================================================
+// Bounded shutdown
+//===========================================================================
+
+// stopGracefully stops the gRPC server from accepting connections and waits
+// for the pending RPCs to finish, forcibly closing them if they haven't
+// finished before the timeout. Returns false if the timeout expired.
+func stopGracefully(srv *grpc.Server, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		srv.Stop()
+		return false
+	}
+}
+
+// waitTimeout waits for the wait group until the timeout expires, returning
+// false if it did.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -3,6 +3,7 @@ package honu
 import (
 	"encoding/json"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -30,12 +31,15 @@ func NewVisibilityLogger(path string) (*VisibilityLogger, error) {
 
 // VisibilityLogger records the time a write becomes visible on the local
 // replica, storing the information on disk. It uses an asynchronous writer
-// so it doesn't block other store operations.
+// so it doesn't block other store operations. Writes that become visible
+// after the logger is closed are not logged.
 type VisibilityLogger struct {
-	file *os.File
-	err  error
-	msgs chan *visibilityMessage
-	done chan bool
+	sync.Mutex
+	file   *os.File
+	err    error
+	msgs   chan *visibilityMessage
+	done   chan bool
+	closed bool
 }
 
 // simple data structure for storing visibility information.
@@ -43,18 +47,32 @@ type visibilityMessage struct {
 	Key       string
 	Version   string
 	Timestamp time.Time
+	Trace     string `json:",omitempty"`
 }
 
-// Log a Put to the key/value store
-func (l *VisibilityLogger) Log(key, version string) {
-	l.msgs <- &visibilityMessage{
-		Key: key, Version: version, Timestamp: time.Now(),
+// Log a Put to the key/value store, with the id of its trace if it's traced
+func (l *VisibilityLogger) Log(key, version, trace string) {
+	l.Lock()
+	defer l.Unlock()
+
+	if !l.closed {
+		l.msgs <- &visibilityMessage{
+			Key: key, Version: version, Timestamp: time.Now(), Trace: trace,
+		}
 	}
 }
 
 // Close the logger and wait until it's done writing all buffered messages.
 func (l *VisibilityLogger) Close() error {
+	l.Lock()
+	if l.closed {
+		l.Unlock()
+		return l.err
+	}
+	l.closed = true
 	close(l.msgs)
+	l.Unlock()
+
 	<-l.done
 
 	if l.err != nil {
@@ -76,13 +94,14 @@ func (l *VisibilityLogger) Error() error {
 // routine that reads visibility log messages off the msgs channel and
 // writes them to disk.
 func (l *VisibilityLogger) flusher() {
+	defer func() { l.done <- true }()
+
 	for msg := range l.msgs {
 		var data []byte
 
 		data, l.err = json.Marshal(msg)
 		if l.err != nil {
 			warne(l.err)
-			close(l.msgs)
 			break
 		}
 
@@ -91,17 +110,17 @@ func (l *VisibilityLogger) flusher() {
 		_, l.err = l.file.Write(data)
 		if l.err != nil {
 			warne(l.err)
-			close(l.msgs)
 			break
 		}
 
 		l.err = l.file.Sync()
 		if l.err != nil {
 			warne(l.err)
-			close(l.msgs)
 			break
 		}
 	}
 
-	l.done <- true
+	// Drain the remaining messages so that callers do not block
+	for range l.msgs {
+	}
 }